@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hey-kong/shift/go-cache-benchmark/zipf"
+)
+
+const (
+	BackendSleep = "sleep"
+	BackendSpin  = "spin"
+)
+
+// Backend models the store behind the cache: every miss waits for a
+// latency drawn from a distribution before the object is set in the cache.
+type Backend struct {
+	// Spec is the distribution, see parseBackend.
+	Spec  string
+	delay func(r *rand.Rand) time.Duration
+	// slots bounds the misses waiting at once, nil for no bound.
+	slots chan struct{}
+	spin  bool
+	seed  int64
+}
+
+// parseBackend parses the latency distribution of a backend:
+//
+//	2ms                 a fixed latency
+//	exp:2ms             exponential, of mean 2ms
+//	uniform:1ms:3ms     uniform between 1ms and 3ms
+//	lognormal:2ms:0.5   log-normal, of mean 2ms and shape (sigma) 0.5
+//
+// Concurrency bounds the misses waiting at once, 0 for no bound. Wait is
+// sleep, cheap but coarse, or spin, precise but burning a core per miss.
+func parseBackend(spec string, concurrency int, wait string, seed int64) (*Backend, error) {
+	b := &Backend{Spec: spec, seed: seed}
+	switch wait {
+	case BackendSleep:
+	case BackendSpin:
+		b.spin = true
+	default:
+		return nil, fmt.Errorf("invalid -backend-wait %q: must be %s or %s", wait, BackendSleep, BackendSpin)
+	}
+	if concurrency < 0 {
+		return nil, fmt.Errorf("invalid -backend-concurrency %d: must not be negative", concurrency)
+	}
+	if concurrency > 0 {
+		b.slots = make(chan struct{}, concurrency)
+	}
+
+	name, args, _ := strings.Cut(spec, ":")
+	params := strings.Split(args, ":")
+	if args == "" {
+		params = nil
+	}
+	duration := func(s string) (time.Duration, error) {
+		d, err := time.ParseDuration(s)
+		if err != nil || d < 0 {
+			return 0, fmt.Errorf("invalid -backend %q: %q is not a duration", spec, s)
+		}
+		return d, nil
+	}
+	arity := func(n int) error {
+		if len(params) != n {
+			return fmt.Errorf("invalid -backend %q: %s takes %d parameters", spec, name, n)
+		}
+		return nil
+	}
+
+	switch name {
+	case "exp":
+		if err := arity(1); err != nil {
+			return nil, err
+		}
+		mean, err := duration(params[0])
+		if err != nil {
+			return nil, err
+		}
+		b.delay = func(r *rand.Rand) time.Duration {
+			return time.Duration(r.ExpFloat64() * float64(mean))
+		}
+	case "uniform":
+		if err := arity(2); err != nil {
+			return nil, err
+		}
+		lo, err := duration(params[0])
+		if err != nil {
+			return nil, err
+		}
+		hi, err := duration(params[1])
+		if err != nil {
+			return nil, err
+		}
+		if hi < lo {
+			return nil, fmt.Errorf("invalid -backend %q: %s is below %s", spec, hi, lo)
+		}
+		b.delay = func(r *rand.Rand) time.Duration {
+			return lo + time.Duration(r.Int63n(int64(hi-lo)+1))
+		}
+	case "lognormal":
+		if err := arity(2); err != nil {
+			return nil, err
+		}
+		mean, err := duration(params[0])
+		if err != nil {
+			return nil, err
+		}
+		sigma, err := strconv.ParseFloat(params[1], 64)
+		if err != nil || sigma < 0 {
+			return nil, fmt.Errorf("invalid -backend %q: %q is not a non-negative shape", spec, params[1])
+		}
+		// the mean of a log-normal is exp(mu + sigma^2/2).
+		mu := math.Log(float64(mean)) - sigma*sigma/2
+		b.delay = func(r *rand.Rand) time.Duration {
+			return time.Duration(math.Exp(mu + sigma*r.NormFloat64()))
+		}
+	default:
+		if err := arity(0); err != nil {
+			return nil, err
+		}
+		d, err := duration(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -backend %q: must be a duration, exp:mean, uniform:min:max or lognormal:mean:sigma", spec)
+		}
+		b.delay = func(*rand.Rand) time.Duration {
+			return d
+		}
+	}
+	return b, nil
+}
+
+// String describes b for the results, "" if b is nil.
+func (b *Backend) String() string {
+	if b == nil {
+		return ""
+	}
+	s := b.Spec
+	if b.slots != nil {
+		s += fmt.Sprintf(" concurrency=%d", cap(b.slots))
+	}
+	if b.spin {
+		s += " " + BackendSpin
+	}
+	return s
+}
+
+// rand returns the random source of worker k, so that workers draw
+// independent latencies without sharing a lock.
+func (b *Backend) rand(k int) *rand.Rand {
+	return rand.New(rand.NewSource(zipf.DeriveSeed(b.seed, k)))
+}
+
+// fetch waits for a latency drawn from r, after a free slot if the
+// concurrency of the backend is bounded.
+func (b *Backend) fetch(r *rand.Rand) {
+	d := b.delay(r)
+	if b.slots != nil {
+		b.slots <- struct{}{}
+		defer func() { <-b.slots }()
+	}
+	if !b.spin {
+		time.Sleep(d)
+		return
+	}
+	for start := time.Now(); time.Since(start) < d; {
+	}
+}
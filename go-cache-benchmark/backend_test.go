@@ -0,0 +1,109 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestParseBackend(t *testing.T) {
+	tests := []struct {
+		spec        string
+		concurrency int
+		wait        string
+		err         bool
+		str         string
+		// mean, lo and hi bound the drawn latencies, their mean within 5%.
+		mean, lo, hi time.Duration
+	}{
+		{spec: "2ms", wait: BackendSleep, str: "2ms", mean: 2 * time.Millisecond, lo: 2 * time.Millisecond, hi: 2 * time.Millisecond},
+		{spec: "0s", wait: BackendSleep, str: "0s"},
+		{spec: "exp:2ms", wait: BackendSleep, str: "exp:2ms", mean: 2 * time.Millisecond, hi: time.Second},
+		{spec: "uniform:1ms:3ms", concurrency: 4, wait: BackendSleep, str: "uniform:1ms:3ms concurrency=4",
+			mean: 2 * time.Millisecond, lo: time.Millisecond, hi: 3 * time.Millisecond},
+		{spec: "uniform:1ms:1ms", wait: BackendSpin, str: "uniform:1ms:1ms spin", mean: time.Millisecond, lo: time.Millisecond, hi: time.Millisecond},
+		{spec: "lognormal:2ms:0.5", wait: BackendSpin, str: "lognormal:2ms:0.5 spin", mean: 2 * time.Millisecond, hi: time.Second},
+		{spec: "lognormal:2ms:0", wait: BackendSleep, str: "lognormal:2ms:0", mean: 2 * time.Millisecond, lo: 2*time.Millisecond - 1, hi: 2*time.Millisecond + 1},
+
+		{spec: "2ms", wait: "busy", err: true},
+		{spec: "2ms", concurrency: -1, wait: BackendSleep, err: true},
+		{spec: "", wait: BackendSleep, err: true},
+		{spec: "-1ms", wait: BackendSleep, err: true},
+		{spec: "2", wait: BackendSleep, err: true},
+		{spec: "2ms:1", wait: BackendSleep, err: true},
+		{spec: "exp", wait: BackendSleep, err: true},
+		{spec: "exp:2ms:1", wait: BackendSleep, err: true},
+		{spec: "exp:fast", wait: BackendSleep, err: true},
+		{spec: "uniform:3ms:1ms", wait: BackendSleep, err: true},
+		{spec: "uniform:1ms", wait: BackendSleep, err: true},
+		{spec: "lognormal:2ms:-1", wait: BackendSleep, err: true},
+		{spec: "lognormal:2ms:wide", wait: BackendSleep, err: true},
+		{spec: "normal:2ms:1ms", wait: BackendSleep, err: true},
+	}
+	for _, tt := range tests {
+		b, err := parseBackend(tt.spec, tt.concurrency, tt.wait, 1)
+		if (err != nil) != tt.err {
+			t.Errorf("%q: error %v", tt.spec, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if b.String() != tt.str {
+			t.Errorf("%q: described as %q, want %q", tt.spec, b.String(), tt.str)
+		}
+
+		const n = 100000
+		r := b.rand(0)
+		var sum float64
+		for i := 0; i < n; i++ {
+			d := b.delay(r)
+			if d < tt.lo || d > tt.hi {
+				t.Errorf("%q: drew %v, out of [%v, %v]", tt.spec, d, tt.lo, tt.hi)
+				break
+			}
+			sum += float64(d)
+		}
+		if mean := sum / n; math.Abs(mean-float64(tt.mean)) > 0.05*float64(tt.mean) {
+			t.Errorf("%q: mean %v, want %v", tt.spec, time.Duration(mean), tt.mean)
+		}
+	}
+
+	var b *Backend
+	if b.String() != "" {
+		t.Errorf("no backend described as %q", b.String())
+	}
+}
+
+func TestBackendConcurrency(t *testing.T) {
+	const workers, latency = 4, 2 * time.Millisecond
+	tests := []struct {
+		concurrency int
+		// at least is the time workers take to fetch once each.
+		atLeast time.Duration
+	}{
+		{0, latency},
+		{1, workers * latency},
+		{2, workers / 2 * latency},
+	}
+	for _, tt := range tests {
+		b, err := parseBackend(latency.String(), tt.concurrency, BackendSleep, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		done := make(chan struct{})
+		for k := 0; k < workers; k++ {
+			go func(k int) {
+				b.fetch(b.rand(k))
+				done <- struct{}{}
+			}(k)
+		}
+		for k := 0; k < workers; k++ {
+			<-done
+		}
+		if elapsed := time.Since(start); elapsed < tt.atLeast {
+			t.Errorf("concurrency %d: %d fetches took %v, want at least %v", tt.concurrency, workers, elapsed, tt.atLeast)
+		}
+	}
+}
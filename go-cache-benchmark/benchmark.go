@@ -2,67 +2,343 @@ package main
 
 import (
 	"fmt"
-	"os"
+	"io"
 	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/olekukonko/tablewriter"
 )
 
 type BenchmarkResult struct {
-	CacheName string
-	Duration  time.Duration
-	Hits      int64
-	Misses    int64
+	CacheName string        `json:"cache"`
+	Duration  time.Duration `json:"duration_ns"`
+	Hits      int64         `json:"hits"`
+	Misses    int64         `json:"misses"`
+	// ByteHits and ByteMisses sum the object sizes of the hits and misses,
+	// of traces with sizes. ByteHits is the backend traffic saved.
+	ByteHits   int64 `json:"byte_hits,omitempty"`
+	ByteMisses int64 `json:"byte_misses,omitempty"`
+	// Memory is the heap retained by the cache after the workload, -1 if
+	// it could not be measured and 0 for simulations, which do not measure
+	// it. Entries is its capacity in entries, and Capacity in bytes with
+	// -bytes, the number of entries of the caches sized in entries times
+	// the mean object size.
+	Memory   int64 `json:"memory_bytes"`
+	Entries  int   `json:"entries"`
+	Capacity int64 `json:"capacity_bytes,omitempty"`
+	// Latency is nil unless operations were timed.
+	Latency *Latency `json:"latency,omitempty"`
+	// Ops breaks the result down by operation kind, for workloads
+	// with more than cache-aside reads.
+	Ops []*OpResult `json:"ops,omitempty"`
+	// Windows is the hit rate and the throughput over the run, nil unless
+	// runs are split into windows.
+	Windows []Window `json:"windows,omitempty"`
 }
 
 func (br *BenchmarkResult) hitRate() float64 {
 	return float64(br.Hits) / float64(br.Hits+br.Misses) * 100
 }
 
+// byteHitRate is the hit rate weighted by the object sizes.
+func (br *BenchmarkResult) byteHitRate() float64 {
+	return float64(br.ByteHits) / float64(br.ByteHits+br.ByteMisses) * 100
+}
+
+// hasBytes reports whether the object sizes of the requests are known.
+func (br *BenchmarkResult) hasBytes() bool {
+	return br.ByteHits+br.ByteMisses > 0
+}
+
+// operations is the number of requests replayed.
+func (br *BenchmarkResult) operations() int64 {
+	if br.Ops == nil {
+		return br.Hits + br.Misses
+	}
+	var n int64
+	for _, op := range br.Ops {
+		n += op.Count
+	}
+	return n
+}
+
+// hasMemory reports whether Memory was measured. Reports of older
+// versions have 0 where the heap shrank during the run.
+func (br *BenchmarkResult) hasMemory() bool {
+	return br.Memory > 0
+}
+
+// memoryPerCapacity is Memory spread over the capacity of the cache: in
+// bytes per entry, or in percent of the capacity in bytes with -bytes.
+func (br *BenchmarkResult) memoryPerCapacity() float64 {
+	if br.Capacity > 0 {
+		return float64(br.Memory) / float64(br.Capacity) * 100
+	}
+	if br.Entries <= 0 {
+		return 0
+	}
+	return float64(br.Memory) / float64(br.Entries)
+}
+
+// formatMemory formats memoryPerCapacity, n/a if Memory was not measured.
+func (br *BenchmarkResult) formatMemory() string {
+	if !br.hasMemory() {
+		return "n/a"
+	}
+	if br.Capacity > 0 {
+		return fmt.Sprintf("%.2f%%", br.memoryPerCapacity())
+	}
+	return fmt.Sprintf("%.1f", br.memoryPerCapacity())
+}
+
+// memoryHeader is the header of the memory of the results of b: per entry,
+// or per byte of capacity when the caches are sized in bytes.
+func (b *Benchmark) memoryHeader() string {
+	if b.ByteCapacity {
+		return "Memory/Capacity"
+	}
+	return "Bytes/Entry"
+}
+
+func (br *BenchmarkResult) qps(n int64) float64 {
+	return float64(n) / br.Duration.Seconds()
+}
+
+// counts are the hits and misses of a worker, in requests and in bytes.
+type counts struct {
+	hits, misses         int64
+	byteHits, byteMisses int64
+}
+
+func (c *counts) add(hit bool, size uint32) {
+	if hit {
+		c.hits++
+		c.byteHits += int64(size)
+	} else {
+		c.misses++
+		c.byteMisses += int64(size)
+	}
+}
+
+func (c *counts) merge(o counts) {
+	c.hits += o.hits
+	c.misses += o.misses
+	c.byteHits += o.byteHits
+	c.byteMisses += o.byteMisses
+}
+
+// result returns the result of a run of cacheName with the counts of c.
+func (c *counts) result(cacheName string) *BenchmarkResult {
+	return &BenchmarkResult{
+		CacheName:  cacheName,
+		Hits:       c.hits,
+		Misses:     c.misses,
+		ByteHits:   c.byteHits,
+		ByteMisses: c.byteMisses,
+	}
+}
+
 type Benchmark struct {
-	ItemSize            int
-	CacheSizeMultiplier float64
-	ZipfAlpha           float64
-	Concurrency         int
-	Results             []*BenchmarkResult
+	ItemSize            int     `json:"item_size"`
+	Workloads           int     `json:"workloads"`
+	CacheSizeMultiplier float64 `json:"cache_ratio"`
+	ZipfAlpha           float64 `json:"alpha,omitempty"`
+	Generator           string  `json:"generator,omitempty"`
+	// GeneratorOptions are the options that change the keys of Generator,
+	// such as "loop-factor=1.1", empty if none does.
+	GeneratorOptions string `json:"generator_options,omitempty"`
+	Trace            string `json:"trace,omitempty"`
+	// ByteCapacity is set when CacheSizeMultiplier is a fraction of the
+	// bytes of the distinct objects rather than of their number.
+	ByteCapacity bool `json:"byte_capacity,omitempty"`
+	// Backend is the latency model of the misses, empty for none.
+	Backend string `json:"backend,omitempty"`
+	// Warmup is the number of requests replayed before the measured ones,
+	// they are not counted in the results.
+	Warmup int `json:"warmup,omitempty"`
+	// Window is the size of the windows of the results, empty for none.
+	Window      string             `json:"window,omitempty"`
+	Concurrency int                `json:"concurrency"`
+	Results     []*BenchmarkResult `json:"results"`
 }
 
 func (b *Benchmark) AddResult(r *BenchmarkResult) {
 	b.Results = append(b.Results, r)
 }
 
-func (b *Benchmark) WriteToConsole() {
-	b.sortResults()
+// WriteTable writes the results as a table for the console.
+func (b *Benchmark) WriteTable(w io.Writer) {
+	if b.Trace != "" {
+		unit := ""
+		if b.ByteCapacity {
+			unit = " of bytes"
+		}
+		fmt.Fprintf(w, "trace=%s, itemSize=%d, workloads=%d, cacheSize=%.2f%%%s, concurrency=%d",
+			b.Trace,
+			b.ItemSize,
+			b.Workloads,
+			b.CacheSizeMultiplier*100,
+			unit,
+			b.Concurrency)
+	} else if b.Generator != "zipf" {
+		fmt.Fprintf(w, "generator=%s, itemSize=%d, workloads=%d, cacheSize=%.2f%%",
+			b.Generator,
+			b.ItemSize,
+			b.Workloads,
+			b.CacheSizeMultiplier*100)
+		if b.ZipfAlpha > 0 {
+			fmt.Fprintf(w, ", zipf's alpha=%.2f", b.ZipfAlpha)
+		}
+		fmt.Fprintf(w, ", concurrency=%d", b.Concurrency)
+	} else {
+		fmt.Fprintf(w, "itemSize=%d, workloads=%d, cacheSize=%.2f%%, zipf's alpha=%.2f, concurrency=%d",
+			b.ItemSize,
+			b.Workloads,
+			b.CacheSizeMultiplier*100,
+			b.ZipfAlpha,
+			b.Concurrency)
+	}
+	if b.GeneratorOptions != "" {
+		fmt.Fprintf(w, ", %s", b.GeneratorOptions)
+	}
+	if b.Backend != "" {
+		fmt.Fprintf(w, ", backend=%s", b.Backend)
+	}
+	if b.Warmup > 0 {
+		fmt.Fprintf(w, ", warmup=%d", b.Warmup)
+	}
+	fmt.Fprintf(w, "\n\n")
 
-	workloads := b.ItemSize * workloadMultiplier
+	// the byte hit rate and the bytes saved are only known with object sizes.
+	hasBytes := false
+	for _, ret := range b.Results {
+		hasBytes = hasBytes || ret.hasBytes()
+	}
 
-	fmt.Printf("itemSize=%d, workloads=%d, cacheSize=%.2f%%, zipf's alpha=%.2f, concurrency=%d\n\n",
-		b.ItemSize,
-		workloads,
-		b.CacheSizeMultiplier*100,
-		b.ZipfAlpha,
-		b.Concurrency)
+	// requests with a backend are timed end to end.
+	hasBackend := b.Backend != ""
 
-	headers := []string{"Cache", "HitRate", "QPS", "Hits", "Misses"}
-	table := tablewriter.NewWriter(os.Stdout)
+	headers := []string{"Cache", "HitRate", "QPS", b.memoryHeader(), "Hits", "Misses"}
+	if hasBytes {
+		headers = append(headers, "ByteHitRate", "BytesSaved")
+	}
+	if hasBackend {
+		headers = append(headers, "Mean", "P99", "P99.9")
+	}
+	table := tablewriter.NewWriter(w)
 	for _, ret := range b.Results {
-		qps := float64(ret.Hits+ret.Misses) / float64(ret.Duration.Milliseconds())
-		qps = qps * 1000
-
-		table.Append([]string{
+		row := []string{
 			ret.CacheName,
 			fmt.Sprintf("%.2f%%", ret.hitRate()),
-			fmt.Sprintf("%.f", qps),
+			fmt.Sprintf("%.f", ret.qps(ret.operations())),
+			ret.formatMemory(),
 			fmt.Sprintf("%d", ret.Hits),
 			fmt.Sprintf("%d", ret.Misses),
-		})
+		}
+		if ret.hasBytes() {
+			row = append(row, fmt.Sprintf("%.2f%%", ret.byteHitRate()), formatBytes(ret.ByteHits))
+		} else if hasBytes {
+			row = append(row, "-", "-")
+		}
+		if e2e, ok := ret.endToEnd(); ok {
+			row = append(row,
+				formatNanos(e2e.Mean),
+				formatNanos(float64(e2e.P99)),
+				formatNanos(float64(e2e.P999)))
+		} else if hasBackend {
+			row = append(row, "-", "-", "-")
+		}
+		table.Append(row)
+		for _, op := range ret.Ops {
+			hitRate := "-"
+			if op.Hits+op.Misses > 0 {
+				hitRate = fmt.Sprintf("%.2f%%", op.hitRate())
+			}
+			row := []string{
+				"  " + op.Op,
+				hitRate,
+				fmt.Sprintf("%.f", ret.qps(op.Count)),
+				"-",
+				fmt.Sprintf("%d", op.Hits),
+				fmt.Sprintf("%d", op.Misses),
+			}
+			for len(row) < len(headers) {
+				row = append(row, "-")
+			}
+			table.Append(row)
+		}
 	}
 	table.SetHeader(headers)
 	table.SetBorder(false)
 	table.Render()
 
-	fmt.Printf("\n\n")
+	b.writeLatency(w)
+	b.writeWindows(w)
+
+	fmt.Fprintf(w, "\n\n")
+}
+
+// formatNanos formats a duration of ns nanoseconds with 3 significant digits.
+func formatNanos(ns float64) string {
+	for _, u := range []struct {
+		unit string
+		ns   float64
+	}{{"s", 1e9}, {"ms", 1e6}, {"µs", 1e3}} {
+		if ns >= u.ns {
+			return strconv.FormatFloat(ns/u.ns, 'g', 3, 64) + u.unit
+		}
+	}
+	return strconv.FormatFloat(ns, 'f', 0, 64) + "ns"
+}
+
+// formatBytes formats n bytes with a binary unit.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// writeLatency prints the latency percentiles of the results that have them.
+func (b *Benchmark) writeLatency(w io.Writer) {
+	table := tablewriter.NewWriter(w)
+	for _, ret := range b.Results {
+		if ret.Latency == nil {
+			continue
+		}
+		for _, op := range latencyOps {
+			l, ok := ret.Latency.summary(op)
+			if !ok {
+				continue
+			}
+			table.Append([]string{
+				ret.CacheName,
+				strings.ReplaceAll(op, "_", " "),
+				fmt.Sprintf("%d", l.Count),
+				fmt.Sprintf("%.f", l.Mean),
+				fmt.Sprintf("%d", l.P50),
+				fmt.Sprintf("%d", l.P90),
+				fmt.Sprintf("%d", l.P99),
+				fmt.Sprintf("%d", l.P999),
+				fmt.Sprintf("%d", l.Max),
+			})
+		}
+	}
+	if table.NumLines() == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nlatency (ns)\n\n")
+	table.SetHeader([]string{"Cache", "Op", "Samples", "Mean", "P50", "P90", "P99", "P99.9", "Max"})
+	table.SetBorder(false)
+	table.Render()
 }
 
 func (b *Benchmark) Clean() {
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"container/heap"
+	"math"
+)
+
+// Oracle is implemented by offline policies that need to know the future.
+// Before every Get, the harness calls SetNextAccess with the virtual time
+// (request index) at which the key of the current request is accessed next,
+// or a negative value if it is never accessed again.
+//
+// Oracle caches are not safe for concurrent use and are always replayed by a
+// single goroutine, in the original request order.
+type Oracle interface {
+	Cache
+	SetNextAccess(vtime int64)
+}
+
+// beladyItem is a resident object ordered by its next access time.
+type beladyItem struct {
+	key   string
+	size  int64
+	next  int64
+	index int
+}
+
+// beladyHeap is a max-heap on the next access time.
+type beladyHeap []*beladyItem
+
+func (h beladyHeap) Len() int           { return len(h) }
+func (h beladyHeap) Less(i, j int) bool { return h[i].next > h[j].next }
+func (h beladyHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *beladyHeap) Push(x any) {
+	item := x.(*beladyItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *beladyHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// Belady is Belady's MIN algorithm: on eviction it discards the object whose
+// next access is furthest in the future. It gives the optimal hit ratio for
+// a given cache size and is only meant as an upper bound in simulations.
+//
+// With a capacity in bytes it evicts in the same order until the new object
+// fits. Finding the optimal byte hit ratio is NP-hard, this is only a close
+// upper bound.
+type Belady struct {
+	// capacity and used are in entries of size 1, or in bytes.
+	capacity int64
+	used     int64
+	next     int64
+	items    map[string]*beladyItem
+	heap     beladyHeap
+}
+
+func NewBelady(size int) Cache {
+	return &Belady{
+		capacity: int64(size),
+		next:     math.MaxInt64,
+		items:    make(map[string]*beladyItem, size),
+		heap:     make(beladyHeap, 0, size),
+	}
+}
+
+// NewSizedBelady returns Belady's MIN with a capacity of capacity bytes.
+func NewSizedBelady(capacity int64) Cache {
+	return &Belady{
+		capacity: capacity,
+		next:     math.MaxInt64,
+		items:    make(map[string]*beladyItem),
+	}
+}
+
+func (c *Belady) Name() string {
+	return "optimal"
+}
+
+func (c *Belady) SetNextAccess(vtime int64) {
+	if vtime < 0 {
+		vtime = math.MaxInt64
+	}
+	c.next = vtime
+}
+
+func (c *Belady) Get(key string) bool {
+	item, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	item.next = c.next
+	heap.Fix(&c.heap, item.index)
+	return true
+}
+
+func (c *Belady) Set(key string) {
+	c.SetSize(key, 1)
+}
+
+func (c *Belady) SetSize(key string, size int64) {
+	size = max(size, 1)
+	if item, ok := c.items[key]; ok {
+		item.next = c.next
+		c.used += size - item.size
+		item.size = size
+		heap.Fix(&c.heap, item.index)
+		// a larger object may no longer fit.
+		for c.used > c.capacity {
+			c.evict()
+		}
+		return
+	}
+	if size > c.capacity || c.next == math.MaxInt64 {
+		// never accessed again, caching it cannot produce a hit.
+		return
+	}
+
+	for c.used+size > c.capacity {
+		// bypass the cache if the new object is the one reused furthest
+		// away. The objects evicted so far were reused even later.
+		if c.heap[0].next <= c.next {
+			return
+		}
+		c.evict()
+	}
+
+	item := &beladyItem{key: key, size: size, next: c.next}
+	heap.Push(&c.heap, item)
+	c.items[key] = item
+	c.used += size
+}
+
+// evict discards the object reused furthest away.
+func (c *Belady) evict() {
+	victim := heap.Pop(&c.heap).(*beladyItem)
+	delete(c.items, victim.key)
+	c.used -= victim.size
+}
+
+func (c *Belady) Close() {
+
+}
+
+// NextAccess computes the next access time of every request in keys, in the
+// format expected by Oracle.SetNextAccess.
+func NextAccess(keys []string) []int64 {
+	next := make([]int64, len(keys))
+	last := make(map[string]int64)
+	for i := len(keys) - 1; i >= 0; i-- {
+		if j, ok := last[keys[i]]; ok {
+			next[i] = j
+		} else {
+			next[i] = -1
+		}
+		last[keys[i]] = int64(i)
+	}
+	return next
+}
@@ -17,6 +17,24 @@ func NewOtter(size int) Cache {
 	return &Otter{v: cache}
 }
 
+// NewSizedOtter returns an otter cache of capacity bytes, every object
+// costs its size.
+func NewSizedOtter(capacity int64) Cache {
+	cache, err := otter.MustBuilder[string, any](int(max(capacity, 1))).
+		Cost(func(key string, value any) uint32 {
+			if size, ok := value.(uint32); ok {
+				return size
+			}
+			return 1
+		}).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+
+	return &Otter{v: cache}
+}
+
 func (c *Otter) Name() string {
 	return "otter"
 }
@@ -30,6 +48,10 @@ func (c *Otter) Set(key string) {
 	c.v.Set(key, key)
 }
 
-func (c *Otter) Close() {
+func (c *Otter) SetSize(key string, size int64) {
+	c.v.Set(key, uint32(min(max(size, 1), 1<<32-1)))
+}
 
+func (c *Otter) Close() {
+	c.v.Close()
 }
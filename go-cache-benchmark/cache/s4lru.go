@@ -12,9 +12,11 @@ type S4LRU struct {
 	v *s4lru.Cache
 }
 
+// NewS4LRU rounds size up to a multiple of 4, the 4 segments of s4lru are
+// of the same size.
 func NewS4LRU(size int) Cache {
 	return &S4LRU{
-		v: s4lru.New(size),
+		v: s4lru.New((max(size, 1) + 3) / 4 * 4),
 	}
 }
 
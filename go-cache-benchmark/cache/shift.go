@@ -6,15 +6,31 @@ import (
 )
 
 type Shift struct {
-	v fifo.Cache[string, any]
+	name string
+	v    fifo.Cache[string, any]
 }
 
 func NewShift(size int) Cache {
-	return &Shift{shift.New[string, any](size)}
+	return &Shift{"shift", shift.New[string, any](size)}
+}
+
+// NewShift3Q is Shift with three frequency-tiered queues.
+func NewShift3Q(size int) Cache {
+	return &Shift{"shift-3q", shift.NewWithConfig[string, any](size, shift.Config{Queues: 3})}
+}
+
+// NewShift4Q is Shift with four frequency-tiered queues.
+func NewShift4Q(size int) Cache {
+	return &Shift{"shift-4q", shift.NewWithConfig[string, any](size, shift.Config{Queues: 4})}
+}
+
+// NewShiftClock is the CLOCK-style Shift that never reorders on hit.
+func NewShiftClock(size int) Cache {
+	return &Shift{"shift-clock", shift.NewClock[string, any](size)}
 }
 
 func (s *Shift) Name() string {
-	return "shift"
+	return s.name
 }
 
 func (s *Shift) Get(key string) bool {
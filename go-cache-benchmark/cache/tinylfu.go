@@ -11,7 +11,10 @@ type TinyLFU[V any] struct {
 	v  *tinylfu.T[string]
 }
 
+// NewTinyLFU needs at least 3 entries: one for the window and one for each
+// segment of the main cache, smaller sizes are rounded up.
 func NewTinyLFU(size int) Cache {
+	size = max(size, 3)
 	return &TinyLFU[any]{
 		v: tinylfu.New[string](size, size*10),
 	}
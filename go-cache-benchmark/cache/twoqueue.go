@@ -6,8 +6,10 @@ type TwoQueue struct {
 	v *lru.TwoQueueCache[string, string]
 }
 
+// NewTwoQueue needs at least 4 entries, its recent queue holds a quarter of
+// them, smaller sizes are rounded up.
 func NewTwoQueue(size int) Cache {
-	v, err := lru.New2Q[string, string](size)
+	v, err := lru.New2Q[string, string](max(size, 4))
 	if err != nil {
 		panic(err)
 	}
@@ -6,3 +6,11 @@ type Cache interface {
 	Set(key string)
 	Close()
 }
+
+// Sized is implemented by caches whose capacity is in bytes rather than in
+// entries. The harness calls SetSize instead of Set when it knows the size
+// of the object, Set stores an object of size 1.
+type Sized interface {
+	Cache
+	SetSize(key string, size int64)
+}
@@ -0,0 +1,290 @@
+// Package chart renders line and bar charts as SVG, with nothing but the
+// standard library.
+package chart
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strconv"
+)
+
+const (
+	width        = 800
+	height       = 480
+	marginLeft   = 80
+	marginRight  = 170
+	marginTop    = 50
+	marginBottom = 60
+	plotWidth    = width - marginLeft - marginRight
+	plotHeight   = height - marginTop - marginBottom
+)
+
+// palette is Tableau 10, distinct enough for a dozen series.
+var palette = []string{
+	"#4e79a7", "#f28e2b", "#e15759", "#76b7b2", "#59a14f",
+	"#edc948", "#b07aa1", "#ff9da7", "#9c755f", "#bab0ac",
+	"#1f77b4", "#d62728",
+}
+
+// Color returns the color of the i-th series.
+func Color(i int) string {
+	return palette[i%len(palette)]
+}
+
+// Point is a point of a line.
+type Point struct {
+	X, Y float64
+}
+
+// Series is a named line.
+type Series struct {
+	Name   string
+	Points []Point
+}
+
+// Axis describes an axis of a chart.
+type Axis struct {
+	Label string
+	// Log scales the axis logarithmically, its values must be positive.
+	Log bool
+	// Format formats the tick values, FormatNumber by default.
+	Format func(float64) string
+}
+
+func (a Axis) format(v float64) string {
+	if a.Format != nil {
+		return a.Format(v)
+	}
+	return FormatNumber(v)
+}
+
+// Line is a line chart, with a marker at every point.
+type Line struct {
+	Title  string
+	X, Y   Axis
+	Series []Series
+}
+
+// Bar is a bar chart, with a bar per label.
+type Bar struct {
+	Title  string
+	Y      Axis
+	Labels []string
+	Values []float64
+}
+
+// FormatNumber formats v with 3 significant digits and k, M or G suffixes.
+func FormatNumber(v float64) string {
+	abs := math.Abs(v)
+	switch {
+	case abs >= 1e9:
+		return strconv.FormatFloat(v/1e9, 'g', 3, 64) + "G"
+	case abs >= 1e6:
+		return strconv.FormatFloat(v/1e6, 'g', 3, 64) + "M"
+	case abs >= 1e3:
+		return strconv.FormatFloat(v/1e3, 'g', 3, 64) + "k"
+	}
+	return strconv.FormatFloat(v, 'g', 3, 64)
+}
+
+// FormatPercent formats v, a percentage, with a % suffix.
+func FormatPercent(v float64) string {
+	return strconv.FormatFloat(v, 'g', 3, 64) + "%"
+}
+
+// scale maps values of an axis to pixels.
+type scale struct {
+	lo, hi float64
+	log    bool
+	ticks  []float64
+}
+
+func newScale(lo, hi float64, log, fromZero bool) scale {
+	if log {
+		lo, hi = math.Log10(lo), math.Log10(hi)
+		if lo == hi {
+			lo, hi = lo-0.5, hi+0.5
+		}
+		return scale{lo: lo, hi: hi, log: true, ticks: logTicks(lo, hi)}
+	}
+	if fromZero {
+		lo = min(lo, 0)
+	}
+	if lo == hi {
+		lo, hi = lo-1, hi+1
+	}
+	ticks, step := niceTicks(lo, hi)
+	return scale{
+		lo:    math.Floor(lo/step) * step,
+		hi:    math.Ceil(hi/step) * step,
+		ticks: ticks,
+	}
+}
+
+// at returns the position of v in [0, 1].
+func (s scale) at(v float64) float64 {
+	if s.log {
+		v = math.Log10(v)
+	}
+	return (v - s.lo) / (s.hi - s.lo)
+}
+
+// niceTicks returns about 6 ticks covering [lo, hi] at a step of 1, 2 or 5
+// times a power of ten, and the step.
+func niceTicks(lo, hi float64) ([]float64, float64) {
+	raw := (hi - lo) / 6
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	step := mag * 10
+	for _, m := range []float64{1, 2, 5} {
+		if raw <= m*mag {
+			step = m * mag
+			break
+		}
+	}
+	var ticks []float64
+	for i := math.Floor(lo / step); i <= math.Ceil(hi/step); i++ {
+		ticks = append(ticks, i*step)
+	}
+	return ticks, step
+}
+
+// logTicks returns the powers of ten between the exponents lo and hi, and
+// their 2 and 5 multiples when the range spans less than three decades.
+func logTicks(lo, hi float64) []float64 {
+	multiples := []float64{1}
+	if hi-lo < 3 {
+		multiples = []float64{1, 2, 5}
+	}
+	var ticks []float64
+	for e := math.Floor(lo); e <= math.Ceil(hi); e++ {
+		for _, m := range multiples {
+			v := m * math.Pow(10, e)
+			if l := math.Log10(v); l >= lo-1e-9 && l <= hi+1e-9 {
+				ticks = append(ticks, v)
+			}
+		}
+	}
+	return ticks
+}
+
+// svg writes the elements of a chart, and keeps the first error.
+type svg struct {
+	w   *bufio.Writer
+	err error
+}
+
+func newSVG(w io.Writer, title string) *svg {
+	s := &svg{w: bufio.NewWriter(w)}
+	s.printf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="12">`+"\n", width, height, width, height)
+	s.printf(`<rect width="100%%" height="100%%" fill="white"/>` + "\n")
+	s.text(width/2, 28, "middle", 16, title)
+	return s
+}
+
+func (s *svg) printf(format string, args ...any) {
+	if s.err == nil {
+		_, s.err = fmt.Fprintf(s.w, format, args...)
+	}
+}
+
+func (s *svg) text(x, y float64, anchor string, size int, text string) {
+	s.printf(`<text x="%.1f" y="%.1f" text-anchor="%s" font-size="%d">%s</text>`+"\n", x, y, anchor, size, html.EscapeString(text))
+}
+
+func (s *svg) line(x1, y1, x2, y2 float64, stroke string) {
+	s.printf(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="%s"/>`+"\n", x1, y1, x2, y2, stroke)
+}
+
+func (s *svg) close() error {
+	s.printf("</svg>\n")
+	if s.err != nil {
+		return s.err
+	}
+	return s.w.Flush()
+}
+
+// yAxis draws the horizontal grid lines, the ticks and the label of y.
+func (s *svg) yAxis(y scale, axis Axis) {
+	for _, t := range y.ticks {
+		py := marginTop + plotHeight*(1-y.at(t))
+		s.line(marginLeft, py, marginLeft+plotWidth, py, "#e0e0e0")
+		s.text(marginLeft-6, py+4, "end", 12, axis.format(t))
+	}
+	s.printf(`<text x="%d" y="%d" text-anchor="middle" transform="rotate(-90 %d %d)">%s</text>`+"\n",
+		18, marginTop+plotHeight/2, 18, marginTop+plotHeight/2, html.EscapeString(axis.Label))
+	s.line(marginLeft, marginTop, marginLeft, marginTop+plotHeight, "black")
+	s.line(marginLeft, marginTop+plotHeight, marginLeft+plotWidth, marginTop+plotHeight, "black")
+}
+
+// WriteSVG writes the chart to w.
+func (c *Line) WriteSVG(w io.Writer) error {
+	xlo, xhi := math.Inf(1), math.Inf(-1)
+	ylo, yhi := math.Inf(1), math.Inf(-1)
+	for _, series := range c.Series {
+		for _, p := range series.Points {
+			xlo, xhi = min(xlo, p.X), max(xhi, p.X)
+			ylo, yhi = min(ylo, p.Y), max(yhi, p.Y)
+		}
+	}
+	if math.IsInf(xlo, 1) {
+		xlo, xhi, ylo, yhi = 1, 10, 0, 1
+	}
+	x := newScale(xlo, xhi, c.X.Log, false)
+	y := newScale(ylo, yhi, c.Y.Log, true)
+
+	s := newSVG(w, c.Title)
+	for _, t := range x.ticks {
+		px := marginLeft + plotWidth*x.at(t)
+		s.line(px, marginTop, px, marginTop+plotHeight, "#f0f0f0")
+		s.text(px, marginTop+plotHeight+18, "middle", 12, c.X.format(t))
+	}
+	s.text(marginLeft+plotWidth/2, height-14, "middle", 12, c.X.Label)
+	s.yAxis(y, c.Y)
+
+	for i, series := range c.Series {
+		color := Color(i)
+		s.printf(`<polyline fill="none" stroke="%s" stroke-width="2" points="`, color)
+		for _, p := range series.Points {
+			s.printf("%.1f,%.1f ", marginLeft+plotWidth*x.at(p.X), marginTop+plotHeight*(1-y.at(p.Y)))
+		}
+		s.printf(`"/>` + "\n")
+		for _, p := range series.Points {
+			s.printf(`<circle cx="%.1f" cy="%.1f" r="3" fill="%s"><title>%s: %s, %s</title></circle>`+"\n",
+				marginLeft+plotWidth*x.at(p.X), marginTop+plotHeight*(1-y.at(p.Y)), color,
+				html.EscapeString(series.Name), c.X.format(p.X), c.Y.format(p.Y))
+		}
+
+		ly := float64(marginTop + 10 + 20*i)
+		s.printf(`<rect x="%d" y="%.1f" width="20" height="3" fill="%s"/>`+"\n", width-marginRight+15, ly-5.5, color)
+		s.text(width-marginRight+42, ly, "start", 12, series.Name)
+	}
+	return s.close()
+}
+
+// WriteSVG writes the chart to w.
+func (c *Bar) WriteSVG(w io.Writer) error {
+	yhi := 0.0
+	for _, v := range c.Values {
+		yhi = max(yhi, v)
+	}
+	y := newScale(0, yhi, false, true)
+
+	s := newSVG(w, c.Title)
+	s.yAxis(y, c.Y)
+	slot := float64(plotWidth) / float64(max(len(c.Values), 1))
+	for i, v := range c.Values {
+		top := marginTop + plotHeight*(1-y.at(v))
+		x := marginLeft + slot*float64(i) + slot*0.15
+		s.printf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s"><title>%s: %s</title></rect>`+"\n",
+			x, top, slot*0.7, marginTop+plotHeight-top, Color(i), html.EscapeString(c.Labels[i]), c.Y.format(v))
+		s.text(x+slot*0.35, top-4, "middle", 11, c.Y.format(v))
+		cx := x + slot*0.35
+		cy := float64(marginTop + plotHeight + 12)
+		s.printf(`<text x="%.1f" y="%.1f" text-anchor="end" transform="rotate(-35 %.1f %.1f)">%s</text>`+"\n",
+			cx, cy, cx, cy, html.EscapeString(c.Labels[i]))
+	}
+	return s.close()
+}
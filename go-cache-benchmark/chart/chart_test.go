@@ -0,0 +1,131 @@
+package chart
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		v       float64
+		number  string
+		percent string
+	}{
+		{0, "0", "0%"},
+		{0.125, "0.125", "0.125%"},
+		{12.34, "12.3", "12.3%"},
+		{999, "999", "999%"},
+		{1500, "1.5k", "1.5e+03%"},
+		{2_340_000, "2.34M", "2.34e+06%"},
+		{5e9, "5G", "5e+09%"},
+		{-1500, "-1.5k", "-1.5e+03%"},
+	}
+	for _, tt := range tests {
+		if got := FormatNumber(tt.v); got != tt.number {
+			t.Errorf("FormatNumber(%g) = %q, want %q", tt.v, got, tt.number)
+		}
+		if got := FormatPercent(tt.v); got != tt.percent {
+			t.Errorf("FormatPercent(%g) = %q, want %q", tt.v, got, tt.percent)
+		}
+	}
+}
+
+func TestTicks(t *testing.T) {
+	tests := []struct {
+		lo, hi   float64
+		log      bool
+		ticks    []float64
+		from, to float64
+	}{
+		{0, 100, false, []float64{0, 20, 40, 60, 80, 100}, 0, 100},
+		{0, 1, false, []float64{0, 0.2, 0.4, 0.6, 0.8, 1}, 0, 1},
+		// the axis is rounded to the ticks.
+		{3, 17, false, []float64{0, 5, 10, 15, 20}, 0, 20},
+		// a single value is widened to draw an axis.
+		{5, 5, false, []float64{4, 4.5, 5, 5.5, 6}, 4, 6},
+		{1, 1000, true, []float64{1, 10, 100, 1000}, 1, 1000},
+		// less than three decades have their 2 and 5 multiples.
+		{1, 16, true, []float64{1, 2, 5, 10}, 1, 16},
+	}
+	for _, tt := range tests {
+		s := newScale(tt.lo, tt.hi, tt.log, false)
+		if !slices.EqualFunc(s.ticks, tt.ticks, func(a, b float64) bool { return math.Abs(a-b) < 1e-9 }) {
+			t.Errorf("newScale(%g, %g, %v): ticks %v, want %v", tt.lo, tt.hi, tt.log, s.ticks, tt.ticks)
+		}
+		if from, to := s.at(tt.from), s.at(tt.to); math.Abs(from) > 1e-9 || math.Abs(to-1) > 1e-9 {
+			t.Errorf("newScale(%g, %g, %v): %g at %g and %g at %g, want 0 and 1", tt.lo, tt.hi, tt.log, tt.from, from, tt.to, to)
+		}
+	}
+}
+
+func TestWriteSVG(t *testing.T) {
+	tests := []struct {
+		name  string
+		chart interface{ WriteSVG(io.Writer) error }
+		want  []string
+	}{
+		{
+			name: "line",
+			chart: &Line{
+				Title: "Miss ratio <zipf>",
+				X:     Axis{Label: "cache size", Log: true, Format: FormatPercent},
+				Y:     Axis{Label: "miss ratio", Format: FormatPercent},
+				Series: []Series{
+					{Name: "sieve", Points: []Point{{1, 40}, {10, 20}}},
+					{Name: "shift", Points: []Point{{1, 35}, {10, 15}}},
+				},
+			},
+			want: []string{
+				">Miss ratio &lt;zipf&gt;</text>",
+				">cache size</text>",
+				">miss ratio</text>",
+				"<title>sieve: 1%, 40%</title>",
+				"<title>shift: 10%, 15%</title>",
+				`stroke="` + Color(1) + `"`,
+			},
+		},
+		{
+			name:  "empty line",
+			chart: &Line{Title: "empty"},
+			want:  []string{">empty</text>"},
+		},
+		{
+			name: "bar",
+			chart: &Bar{
+				Title:  "Memory per entry",
+				Y:      Axis{Label: "bytes per entry"},
+				Labels: []string{"sieve", "s3-fifo"},
+				Values: []float64{96, 1500},
+			},
+			want: []string{
+				">Memory per entry</text>",
+				">bytes per entry</text>",
+				"<title>sieve: 96</title>",
+				"<title>s3-fifo: 1.5k</title>",
+				`fill="` + Color(1) + `"`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := tt.chart.WriteSVG(&buf); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		svg := buf.String()
+		if !strings.HasPrefix(svg, "<svg ") || !strings.HasSuffix(svg, "</svg>\n") {
+			t.Errorf("%s: not an SVG document:\n%s", tt.name, svg)
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(svg, want) {
+				t.Errorf("%s: no %q in\n%s", tt.name, want, svg)
+			}
+		}
+		if strings.Contains(svg, "NaN") || strings.Contains(svg, "Inf") {
+			t.Errorf("%s: coordinates out of the plot in\n%s", tt.name, svg)
+		}
+	}
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// CompareConfig holds the parameters of the compare command.
+type CompareConfig struct {
+	Old, New []string
+	Caches   []string
+	// HitRate is the largest drop of hit rate, in percentage points,
+	// QPS and Memory the largest relative changes, in percent,
+	// that are not regressions.
+	HitRate float64
+	QPS     float64
+	Memory  float64
+}
+
+func parseCompareConfig(args []string, output io.Writer) (*CompareConfig, error) {
+	c := &CompareConfig{
+		HitRate: 0.1,
+		QPS:     5,
+		Memory:  5,
+	}
+
+	fs := flag.NewFlagSet("go-cache-benchmark compare", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Var((*stringList)(&c.Caches), "caches", "comma-separated `names` of the caches to compare, all by default")
+	fs.Float64Var(&c.HitRate, "hit-rate", c.HitRate, "largest drop of hit rate, in percentage points, that is not a regression")
+	fs.Float64Var(&c.QPS, "qps", c.QPS, "largest drop of QPS, in percent, that is not a regression")
+	fs.Float64Var(&c.Memory, "memory", c.Memory, "largest growth of bytes per entry, in percent, that is not a regression")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go-cache-benchmark compare [flags] old.json[,old2.json...] new.json[,new2.json...]\n\n")
+		fmt.Fprintf(fs.Output(), "Compares two sets of JSON results, written with -format json, and exits with 1 on a regression.\n\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return nil, errors.New("compare needs the old and the new results")
+	}
+	c.Old = strings.Split(fs.Arg(0), ",")
+	c.New = strings.Split(fs.Arg(1), ",")
+	if c.HitRate < 0 || c.QPS < 0 || c.Memory < 0 {
+		return nil, errors.New("-hit-rate, -qps and -memory must not be negative")
+	}
+	return c, nil
+}
+
+// runKey identifies the runs of a cache with the same parameters,
+// across result files.
+type runKey struct {
+	workload    string
+	options     string
+	items       int
+	workloads   int
+	ratio       float64
+	bytes       bool
+	alpha       float64
+	concurrency int
+	backend     string
+	warmup      int
+	window      string
+	cache       string
+}
+
+func (k runKey) String() string {
+	s := k.workload
+	if k.options != "" {
+		s += " " + k.options
+	}
+	s += fmt.Sprintf(" items=%d workloads=%d ratio=%g", k.items, k.workloads, k.ratio)
+	if k.bytes {
+		s += " of bytes"
+	}
+	if k.alpha > 0 {
+		s += fmt.Sprintf(" alpha=%g", k.alpha)
+	}
+	s += fmt.Sprintf(" concurrency=%d", k.concurrency)
+	if k.backend != "" {
+		s += fmt.Sprintf(" backend=%q", k.backend)
+	}
+	if k.warmup > 0 {
+		s += fmt.Sprintf(" warmup=%d", k.warmup)
+	}
+	if k.window != "" {
+		s += fmt.Sprintf(" window=%q", k.window)
+	}
+	return s
+}
+
+// samples are the metrics of the repeated runs of a key.
+type samples struct {
+	hitRate, qps, memory []float64
+}
+
+// loadResults reads JSON reports and groups their results by run, in the
+// order they first appear.
+func loadResults(paths []string) (map[runKey]*samples, []runKey, error) {
+	runs := make(map[runKey]*samples)
+	var keys []runKey
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		var report Report
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, nil, fmt.Errorf("could not read results %s: %w", path, err)
+		}
+		for _, b := range report.Benchmarks {
+			for _, r := range b.Results {
+				k := runKey{
+					workload:    b.workloadName(),
+					options:     b.GeneratorOptions,
+					items:       b.ItemSize,
+					workloads:   b.Workloads,
+					ratio:       b.CacheSizeMultiplier,
+					bytes:       b.ByteCapacity,
+					alpha:       b.ZipfAlpha,
+					concurrency: b.Concurrency,
+					backend:     b.Backend,
+					warmup:      b.Warmup,
+					window:      b.Window,
+					cache:       r.CacheName,
+				}
+				s, ok := runs[k]
+				if !ok {
+					s = &samples{}
+					runs[k] = s
+					keys = append(keys, k)
+				}
+				s.hitRate = append(s.hitRate, r.hitRate())
+				s.qps = append(s.qps, r.qps(r.operations()))
+				// runs that could not measure memory are left out.
+				if r.hasMemory() {
+					s.memory = append(s.memory, r.memoryPerCapacity())
+				}
+			}
+		}
+	}
+	return runs, keys, nil
+}
+
+// change is the difference of a metric between the old and new runs.
+type change struct {
+	old, new float64
+	// delta is new-old in percentage points for hit rates, in percent otherwise.
+	delta float64
+	// regression is a change for the worse beyond the threshold that is not
+	// noise, and noise a change beyond the threshold within the spread of
+	// the repeated runs.
+	regression, noise bool
+}
+
+// compareMetric compares the runs of a metric. Higher values are better
+// unless lowerIsBetter. relative measures delta in percent of the old mean.
+//
+// A change beyond the threshold is a regression only if the old and new runs
+// do not overlap: the worst old run must still beat the best new one. With a
+// single run on each side, any change beyond the threshold is a regression.
+func compareMetric(old, new []float64, threshold float64, relative, lowerIsBetter bool) change {
+	c := change{old: mean(old), new: mean(new)}
+	c.delta = c.new - c.old
+	if relative {
+		if c.old == 0 {
+			c.delta = 0
+		} else {
+			c.delta = c.delta / c.old * 100
+		}
+	}
+
+	worse := c.delta
+	if lowerIsBetter {
+		worse = -worse
+	}
+	if -worse <= threshold {
+		return c
+	}
+	if lowerIsBetter {
+		c.regression = slices.Min(new) > slices.Max(old)
+	} else {
+		c.regression = slices.Max(new) < slices.Min(old)
+	}
+	c.noise = !c.regression
+	return c
+}
+
+func mean(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+// compare prints the changes between the results of c and reports whether
+// any of them is a regression.
+func compare(c *CompareConfig, w io.Writer) (bool, error) {
+	oldRuns, oldKeys, err := loadResults(c.Old)
+	if err != nil {
+		return false, err
+	}
+	newRuns, newKeys, err := loadResults(c.New)
+	if err != nil {
+		return false, err
+	}
+
+	selected := func(k runKey) bool {
+		return len(c.Caches) == 0 || slices.Contains(c.Caches, k.cache)
+	}
+
+	regressed := false
+	var table *tablewriter.Table
+	var benchmark string
+	flush := func() {
+		if table != nil {
+			table.Render()
+			fmt.Fprintf(w, "\n")
+		}
+	}
+	for _, k := range oldKeys {
+		n, ok := newRuns[k]
+		if !ok || !selected(k) {
+			continue
+		}
+		o := oldRuns[k]
+
+		if name := k.String(); name != benchmark {
+			flush()
+			benchmark = name
+			fmt.Fprintf(w, "%s\n\n", name)
+			table = tablewriter.NewWriter(w)
+			memoryHeader := "Bytes/Entry"
+			if k.bytes {
+				memoryHeader = "Memory/Capacity"
+			}
+			table.SetHeader([]string{"Cache", "Runs", "HitRate", "QPS", memoryHeader, "Verdict"})
+			table.SetBorder(false)
+		}
+
+		hitRate := compareMetric(o.hitRate, n.hitRate, c.HitRate, false, false)
+		qps := compareMetric(o.qps, n.qps, c.QPS, true, false)
+		// memory is n/a unless both sides have a run that measured it.
+		var memory change
+		memoryCell := "n/a"
+		if len(o.memory) > 0 && len(n.memory) > 0 {
+			memory = compareMetric(o.memory, n.memory, c.Memory, true, true)
+			// a single reading of the heap can be a one-off jump, either way:
+			// as for QPS, only repeated runs that do not overlap are a change.
+			if math.Abs(memory.delta) > c.Memory && (len(o.memory) < 2 || len(n.memory) < 2) {
+				memory.regression, memory.noise = false, true
+			}
+			if k.bytes {
+				memoryCell = fmt.Sprintf("%.2f%% -> %.2f%% (%+.1f%%)", memory.old, memory.new, memory.delta)
+			} else {
+				memoryCell = fmt.Sprintf("%.1f -> %.1f (%+.1f%%)", memory.old, memory.new, memory.delta)
+			}
+		}
+
+		var regressions, noise []string
+		for _, m := range []struct {
+			name string
+			change
+		}{{"hit rate", hitRate}, {"qps", qps}, {"memory", memory}} {
+			if m.regression {
+				regressions = append(regressions, m.name)
+			} else if m.noise {
+				noise = append(noise, m.name)
+			}
+		}
+		verdict := "ok"
+		if len(regressions) > 0 {
+			regressed = true
+			verdict = "REGRESSION: " + strings.Join(regressions, ", ")
+		} else if len(noise) > 0 {
+			verdict = "noise: " + strings.Join(noise, ", ")
+		}
+
+		table.Append([]string{
+			k.cache,
+			fmt.Sprintf("%d/%d", len(o.hitRate), len(n.hitRate)),
+			fmt.Sprintf("%.2f%% -> %.2f%% (%+.2f)", hitRate.old, hitRate.new, hitRate.delta),
+			fmt.Sprintf("%.f -> %.f (%+.1f%%)", qps.old, qps.new, qps.delta),
+			memoryCell,
+			verdict,
+		})
+	}
+	flush()
+
+	for _, k := range oldKeys {
+		if _, ok := newRuns[k]; !ok && selected(k) {
+			fmt.Fprintf(w, "only in old: %s %s\n", k.cache, k)
+		}
+	}
+	for _, k := range newKeys {
+		if _, ok := oldRuns[k]; !ok && selected(k) {
+			fmt.Fprintf(w, "only in new: %s %s\n", k.cache, k)
+		}
+	}
+	return regressed, nil
+}
+
+// compareMain runs the compare command and returns its exit code.
+func compareMain(args []string) int {
+	c, err := parseCompareConfig(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go-cache-benchmark: %v\n", err)
+		return 2
+	}
+	regressed, err := compare(c, os.Stdout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go-cache-benchmark: %v\n", err)
+		return 2
+	}
+	if regressed {
+		fmt.Fprintf(os.Stderr, "go-cache-benchmark: regression detected\n")
+		return 1
+	}
+	return 0
+}
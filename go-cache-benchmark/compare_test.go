@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompareMetric(t *testing.T) {
+	tests := []struct {
+		name          string
+		old, new      []float64
+		threshold     float64
+		relative      bool
+		lowerIsBetter bool
+		delta         float64
+		regression    bool
+		noise         bool
+	}{
+		// hit rates, in percentage points.
+		{"unchanged", []float64{50}, []float64{50}, 0.1, false, false, 0, false, false},
+		{"drop within threshold", []float64{50}, []float64{49.95}, 0.1, false, false, -0.05, false, false},
+		{"drop at threshold", []float64{50}, []float64{49.75}, 0.25, false, false, -0.25, false, false},
+		{"single run drop", []float64{50}, []float64{49}, 0.1, false, false, -1, true, false},
+		{"gain", []float64{50}, []float64{60}, 0.1, false, false, 10, false, false},
+		{"disjoint runs drop", []float64{50, 51}, []float64{48, 49}, 0.1, false, false, -2, true, false},
+		// the worst old run does not beat the best new one.
+		{"overlapping runs drop", []float64{46, 54}, []float64{47, 49}, 0.1, false, false, -2, false, true},
+		// QPS, in percent of the old mean.
+		{"qps drop within threshold", []float64{1000}, []float64{960}, 5, true, false, -4, false, false},
+		{"qps drop", []float64{1000}, []float64{900}, 5, true, false, -10, true, false},
+		{"qps noisy drop", []float64{800, 1200}, []float64{850, 950}, 5, true, false, -10, false, true},
+		{"qps from zero", []float64{0}, []float64{100}, 5, true, false, 0, false, false},
+		// bytes per entry, lower is better.
+		{"memory shrink", []float64{200}, []float64{100}, 5, true, true, -50, false, false},
+		{"memory growth within threshold", []float64{100}, []float64{104}, 5, true, true, 4, false, false},
+		{"memory growth", []float64{100, 101}, []float64{110, 112}, 5, true, true, 10.44776119402985, true, false},
+		{"memory noisy growth", []float64{90, 110}, []float64{105, 115}, 5, true, true, 10, false, true},
+	}
+	for _, tt := range tests {
+		c := compareMetric(tt.old, tt.new, tt.threshold, tt.relative, tt.lowerIsBetter)
+		if math.Abs(c.delta-tt.delta) > 1e-9 {
+			t.Errorf("%s: delta %g, want %g", tt.name, c.delta, tt.delta)
+		}
+		if c.regression != tt.regression || c.noise != tt.noise {
+			t.Errorf("%s: regression %v, noise %v, want %v, %v", tt.name, c.regression, c.noise, tt.regression, tt.noise)
+		}
+		if c.old != mean(tt.old) || c.new != mean(tt.new) {
+			t.Errorf("%s: means %g -> %g, want %g -> %g", tt.name, c.old, c.new, mean(tt.old), mean(tt.new))
+		}
+	}
+}
+
+func TestCompareMemory(t *testing.T) {
+	// writeResults writes a result of sieve per memory, in bytes.
+	dir := t.TempDir()
+	writeResults := func(name string, memory ...int64) string {
+		b := &Benchmark{Generator: "zipf", ItemSize: 1000, Workloads: 10000, CacheSizeMultiplier: 0.01, Concurrency: 1}
+		for _, m := range memory {
+			b.Results = append(b.Results, &BenchmarkResult{
+				CacheName: "sieve", Duration: time.Second, Hits: 50, Misses: 50, Memory: m, Entries: 10,
+			})
+		}
+		data, err := json.Marshal(Report{Benchmarks: []*Benchmark{b}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name      string
+		old, new  []int64
+		memory    string
+		verdict   string
+		regressed bool
+	}{
+		{"measured", []int64{1000}, []int64{1020}, "100.0 -> 102.0 (+2.0%)", "ok", false},
+		// the runs that could not measure memory are left out of the mean.
+		{"some unmeasured", []int64{1000, -1}, []int64{-1, 1020}, "100.0 -> 102.0 (+2.0%)", "ok", false},
+		// neither -1 nor the 0 of older reports is a drop to no memory.
+		{"unmeasured new", []int64{1000}, []int64{-1}, "n/a", "ok", false},
+		{"zero old", []int64{0}, []int64{1000}, "n/a", "ok", false},
+		// a single reading on a side tells no change from a one-off jump.
+		{"single run growth", []int64{1000}, []int64{2000}, "100.0 -> 200.0 (+100.0%)", "noise: memory", false},
+		{"single run shrink", []int64{1900}, []int64{1670}, "190.0 -> 167.0 (-12.1%)", "noise: memory", false},
+		{"single new run", []int64{1000, 1010}, []int64{2000}, "100.5 -> 200.0 (+99.0%)", "noise: memory", false},
+		{"repeated growth", []int64{1000, 1010}, []int64{2000, 2010}, "100.5 -> 200.5 (+99.5%)", "REGRESSION: memory", true},
+		{"repeated overlapping growth", []int64{1000, 3000}, []int64{2500, 2600}, "200.0 -> 255.0 (+27.5%)", "noise: memory", false},
+		{"repeated shrink", []int64{2000, 2010}, []int64{1000, 1010}, "200.5 -> 100.5 (-49.9%)", "ok", false},
+	}
+	for _, tt := range tests {
+		c := &CompareConfig{
+			Old:     []string{writeResults("old.json", tt.old...)},
+			New:     []string{writeResults("new.json", tt.new...)},
+			HitRate: 0.1,
+			QPS:     5,
+			Memory:  5,
+		}
+		var out bytes.Buffer
+		regressed, err := compare(c, &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if regressed != tt.regressed || !strings.Contains(out.String(), tt.memory) || !strings.Contains(out.String(), tt.verdict) {
+			t.Errorf("%s: regressed %v, want %v, memory %q and %q in\n%s", tt.name, regressed, tt.regressed, tt.memory, tt.verdict, out.String())
+		}
+	}
+}
+
+func TestCompareSettings(t *testing.T) {
+	dir := t.TempDir()
+	writeResults := func(name string, b *Benchmark) string {
+		b.Results = []*BenchmarkResult{{CacheName: "sieve", Duration: time.Second, Hits: 50, Misses: 50, Memory: 1000, Entries: 10}}
+		data, err := json.Marshal(Report{Benchmarks: []*Benchmark{b}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	benchmark := func() *Benchmark {
+		return &Benchmark{Generator: "loop", GeneratorOptions: "loop-factor=1.1", ItemSize: 1000, Workloads: 10000, CacheSizeMultiplier: 0.01, Concurrency: 1}
+	}
+
+	tests := []struct {
+		name   string
+		change func(b *Benchmark)
+		paired bool
+	}{
+		{"same", func(b *Benchmark) {}, true},
+		{"generator options", func(b *Benchmark) { b.GeneratorOptions = "loop-factor=2" }, false},
+		{"window", func(b *Benchmark) { b.Window = "1000" }, false},
+		{"warmup", func(b *Benchmark) { b.Warmup = 100 }, false},
+		{"backend", func(b *Benchmark) { b.Backend = "100µs" }, false},
+		{"byte capacity", func(b *Benchmark) { b.ByteCapacity = true }, false},
+	}
+	for _, tt := range tests {
+		b := benchmark()
+		tt.change(b)
+		c := &CompareConfig{
+			Old:     []string{writeResults("old.json", benchmark())},
+			New:     []string{writeResults("new.json", b)},
+			HitRate: 0.1,
+			QPS:     5,
+			Memory:  5,
+		}
+		var out bytes.Buffer
+		if _, err := compare(c, &out); err != nil {
+			t.Fatal(err)
+		}
+		paired := !strings.Contains(out.String(), "only in")
+		if paired != tt.paired {
+			t.Errorf("%s: paired %v, want %v in\n%s", tt.name, paired, tt.paired, out.String())
+		}
+	}
+}
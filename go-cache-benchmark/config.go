@@ -0,0 +1,387 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Config holds the parameters of a benchmark run.
+// Every list is swept, so a run executes one benchmark per combination.
+type Config struct {
+	Caches []string
+	// Generators are the key generators, see parseGeneratorSpec.
+	Generators       []string
+	GeneratorOptions GeneratorOptions
+	// YCSB replaces the generators by YCSB core workloads, a to f.
+	YCSB               []string
+	YCSBScanLength     int
+	Items              []int
+	Alphas             []float64
+	CacheRatios        []float64
+	Concurrencies      []int
+	WorkloadMultiplier int
+	Seed               int64
+	// LatencyEvery times one in LatencyEvery operations, 0 times none.
+	LatencyEvery int
+	// MRC sweeps MRCPoints cache sizes between the smallest and the largest
+	// of CacheRatios, all of CacheRatios if MRCPoints is 0, and writes the
+	// miss ratio curve of every cache.
+	MRC       bool
+	MRCPoints int
+	// Backend is the latency distribution of the misses, see parseBackend,
+	// empty for none. BackendConcurrency bounds the misses waiting at once,
+	// and BackendWait is how they wait, sleep or spin.
+	Backend            string
+	BackendConcurrency int
+	BackendWait        string
+	// Warmup is the fraction of the requests replayed before the measured
+	// ones. Window splits the measured requests into windows of a number
+	// of requests or of a duration, empty for none.
+	Warmup float64
+	Window string
+	// Count is the number of runs of every benchmark.
+	Count int
+	// Format is the format of the results, written to Output or stdout.
+	Format string
+	Output string
+	// Lazy draws the keys in every goroutine while replaying them,
+	// instead of generating them all in advance.
+	Lazy bool
+	List bool
+
+	// Trace replaces the zipf generator by the requests of a trace file,
+	// Items, Alphas, WorkloadMultiplier and Seed are then ignored.
+	Trace        string
+	TraceOptions TraceOptions
+	TraceLimit   int
+	// Bytes gives the caches that support it a capacity in bytes,
+	// CacheRatios are then fractions of the bytes of the distinct objects.
+	Bytes bool
+	// Stream replays the trace from the file with bounded memory
+	// instead of loading it in memory first.
+	Stream bool
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Generators:         []string{"zipf"},
+		GeneratorOptions:   defaultGeneratorOptions(),
+		YCSBScanLength:     100,
+		Items:              []int{1e5 * 5},
+		Alphas:             []float64{0.99},
+		CacheRatios:        []float64{0.001, 0.01, 0.1},
+		Concurrencies:      []int{1, 2, 4, 8, 16},
+		WorkloadMultiplier: 15,
+		Seed:               19931203,
+		MRCPoints:          32,
+		BackendWait:        BackendSleep,
+		Count:              1,
+		Format:             FormatTable,
+		TraceOptions:       defaultTraceOptions(),
+	}
+}
+
+// parseConfig parses the command line arguments on top of defaultConfig.
+func parseConfig(args []string, output io.Writer) (*Config, error) {
+	c := defaultConfig()
+
+	fs := flag.NewFlagSet("go-cache-benchmark", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.Var((*stringList)(&c.Caches), "caches", "comma-separated `names` of the caches to run, or \"all\" (default: every cache but otter and lfu)")
+	fs.Var((*stringList)(&c.Generators), "gen", "comma-separated key `generators`: zipf, uniform, scan, loop, hotspot, or phases such as zipf:0.9+scan:0.1")
+	fs.Float64Var(&c.GeneratorOptions.LoopFactor, "loop-factor", c.GeneratorOptions.LoopFactor, "length of the loop generator, as a multiple of the cache size")
+	fs.Float64Var(&c.GeneratorOptions.HotspotSize, "hotspot-size", c.GeneratorOptions.HotspotSize, "size of the hot set of the hotspot generator, as a fraction of the number of keys")
+	fs.Float64Var(&c.GeneratorOptions.HotspotProb, "hotspot-prob", c.GeneratorOptions.HotspotProb, "fraction of the requests sent to the hot set of the hotspot generator")
+	fs.Float64Var(&c.GeneratorOptions.HotspotDrift, "hotspot-drift", c.GeneratorOptions.HotspotDrift, "requests between two moves of the hot set by one key, as a fraction of the number of keys, 0 never moves it")
+	fs.Var((*stringList)(&c.YCSB), "ycsb", "comma-separated YCSB core `workloads` (a to f) to run instead of -gen")
+	fs.IntVar(&c.YCSBScanLength, "ycsb-scan", c.YCSBScanLength, "maximum number of records read by a YCSB scan")
+	fs.Var((*intList)(&c.Items), "items", "comma-separated numbers of distinct keys")
+	fs.Var((*floatList)(&c.Alphas), "alphas", "comma-separated zipf's alphas")
+	fs.Var((*floatList)(&c.CacheRatios), "ratios", "comma-separated cache sizes, as fractions of the number of keys")
+	fs.Var((*intList)(&c.Concurrencies), "concurrency", "comma-separated numbers of goroutines")
+	fs.IntVar(&c.WorkloadMultiplier, "workload", c.WorkloadMultiplier, "number of requests, as a multiple of the number of keys")
+	fs.Int64Var(&c.Seed, "seed", c.Seed, "seed of the key generator")
+	fs.IntVar(&c.LatencyEvery, "latency", 0, "time one in `N` operations and report their latency percentiles, 0 for none")
+	fs.BoolVar(&c.Lazy, "lazy", false, "draw the keys of zipf and uniform in every goroutine while replaying them, instead of in advance")
+	fs.BoolVar(&c.MRC, "mrc", false, "write the miss ratio curve of every cache, simulated in parallel at many cache sizes")
+	fs.IntVar(&c.MRCPoints, "mrc-points", c.MRCPoints, "number of cache sizes of -mrc, spaced logarithmically between the smallest and largest -ratios, 0 for -ratios")
+	fs.StringVar(&c.Backend, "backend", "", "latency of a miss: a `distribution` such as 100us, exp:100us, uniform:50us:150us or lognormal:100us:0.5")
+	fs.IntVar(&c.BackendConcurrency, "backend-concurrency", 0, "largest number of misses waiting for -backend at once, 0 for no limit")
+	fs.StringVar(&c.BackendWait, "backend-wait", c.BackendWait, "how misses wait for -backend: sleep, or spin for short latencies")
+	fs.Float64Var(&c.Warmup, "warmup", 0, "`fraction` of the requests replayed to fill the caches before the measured ones")
+	fs.StringVar(&c.Window, "window", "", "report the hit rate and the QPS of windows of `n` requests, such as 1e5, or of a duration, such as 100ms")
+	fs.IntVar(&c.Count, "count", c.Count, "run every benchmark `n` times, compare tells noise from changes with repeated runs")
+	fs.StringVar(&c.Format, "format", c.Format, "format of the results: table, json, csv or markdown")
+	fs.StringVar(&c.Output, "o", "", "write the results to the file at `path` instead of stdout")
+	fs.BoolVar(&c.List, "list", false, "list the available caches and exit")
+	fs.StringVar(&c.Trace, "trace", "", "replay the trace at `path` instead of generating zipf keys")
+	fs.StringVar(&c.TraceOptions.Format, "trace-format", "", "format of -trace: oracleGeneral, csv or txt (default: detected from the file name, .gz and .zst are decompressed)")
+	fs.IntVar(&c.TraceLimit, "trace-limit", 0, "replay at most this many requests of -trace, 0 for all")
+	fs.BoolVar(&c.Bytes, "bytes", false, "size the caches that support it (otter, optimal) in bytes, -ratios of the bytes of the distinct objects of -trace")
+	fs.BoolVar(&c.Stream, "stream", false, "stream -trace from the file on every run instead of loading it in memory")
+	fs.Var((*delimiter)(&c.TraceOptions.CSV.Delimiter), "csv-delimiter", "field delimiter of a CSV trace, \"tab\" for tabs")
+	fs.BoolVar(&c.TraceOptions.CSV.Header, "csv-header", false, "the first line of a CSV trace is a header")
+	fs.StringVar(&c.TraceOptions.CSV.KeyColumn, "csv-key", c.TraceOptions.CSV.KeyColumn, "`column` of the key in a CSV trace, a 1-based index or a header name")
+	fs.StringVar(&c.TraceOptions.CSV.TimeColumn, "csv-time", "", "`column` of the timestamp in a CSV trace")
+	fs.StringVar(&c.TraceOptions.CSV.SizeColumn, "csv-size", "", "`column` of the object size in a CSV trace")
+	fs.StringVar(&c.TraceOptions.CSV.OpColumn, "csv-op", "", "`column` of the operation in a CSV trace")
+	fs.Var((*stringList)(&c.TraceOptions.CSV.Ops), "ops", "comma-separated operations to keep from a CSV trace, all by default")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go-cache-benchmark [flags]\n")
+		fmt.Fprintf(fs.Output(), "       go-cache-benchmark compare [flags] old.json new.json\n")
+		fmt.Fprintf(fs.Output(), "       go-cache-benchmark report [flags] results.json...\n\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) validate() error {
+	if _, err := lookupCaches(c.Caches); err != nil {
+		return err
+	}
+	if len(c.Generators) == 0 {
+		return errors.New("-gen must not be empty")
+	}
+	for _, spec := range c.Generators {
+		names, _, err := parseGeneratorSpec(spec)
+		if err != nil {
+			return fmt.Errorf("invalid -gen: %w", err)
+		}
+		if c.Lazy && (len(names) > 1 || (names[0] != "zipf" && names[0] != "uniform")) {
+			return fmt.Errorf("invalid -gen %q: only zipf and uniform can be drawn with -lazy", spec)
+		}
+	}
+	if o := c.GeneratorOptions; o.LoopFactor <= 0 {
+		return fmt.Errorf("invalid -loop-factor %g: must be positive", o.LoopFactor)
+	} else if o.HotspotSize <= 0 || o.HotspotSize > 1 {
+		return fmt.Errorf("invalid -hotspot-size %g: must be in (0, 1]", o.HotspotSize)
+	} else if o.HotspotProb < 0 || o.HotspotProb > 1 {
+		return fmt.Errorf("invalid -hotspot-prob %g: must be in [0, 1]", o.HotspotProb)
+	} else if o.HotspotDrift < 0 {
+		return fmt.Errorf("invalid -hotspot-drift %g: must not be negative", o.HotspotDrift)
+	}
+	for _, name := range c.YCSB {
+		if _, err := lookupYCSBWorkload(name); err != nil {
+			return fmt.Errorf("invalid -ycsb: %w", err)
+		}
+	}
+	if c.YCSBScanLength <= 0 {
+		return fmt.Errorf("invalid -ycsb-scan %d: must be positive", c.YCSBScanLength)
+	}
+	if len(c.YCSB) > 0 && c.Lazy {
+		return errors.New("-ycsb generates its operations in advance, it cannot be used with -lazy")
+	}
+	if len(c.Items) == 0 || len(c.Alphas) == 0 || len(c.CacheRatios) == 0 || len(c.Concurrencies) == 0 {
+		return errors.New("-items, -alphas, -ratios and -concurrency must not be empty")
+	}
+	for _, n := range c.Items {
+		if n <= 0 {
+			return fmt.Errorf("invalid -items %d: must be positive", n)
+		}
+	}
+	for _, a := range c.Alphas {
+		if a <= 0 {
+			return fmt.Errorf("invalid -alphas %g: must be positive", a)
+		}
+	}
+	for _, r := range c.CacheRatios {
+		if r <= 0 || r > 1 {
+			return fmt.Errorf("invalid -ratios %g: must be in (0, 1]", r)
+		}
+		// the distinct keys of a trace are only known once it is read,
+		// benchmark skips its ratios that round to no entry.
+		if c.Trace != "" {
+			continue
+		}
+		for _, n := range c.Items {
+			if int(float64(n)*r) < 1 {
+				return fmt.Errorf("invalid -ratios %g: %g of %d -items is less than one entry", r, r, n)
+			}
+		}
+	}
+	for _, n := range c.Concurrencies {
+		if n <= 0 {
+			return fmt.Errorf("invalid -concurrency %d: must be positive", n)
+		}
+	}
+	format := c.TraceOptions.Format
+	if format == "" && c.Trace != "" {
+		var err error
+		if format, err = detectTraceFormat(c.Trace); err != nil {
+			return err
+		}
+	}
+	switch format {
+	case "", TraceFormatOracleGeneral, TraceFormatText:
+	case TraceFormatCSV:
+		if err := c.TraceOptions.CSV.validate(); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("invalid -trace-format %q: must be %s, %s or %s",
+			c.TraceOptions.Format, TraceFormatOracleGeneral, TraceFormatCSV, TraceFormatText)
+	}
+	if c.TraceLimit < 0 {
+		return fmt.Errorf("invalid -trace-limit %d: must not be negative", c.TraceLimit)
+	}
+	if c.Bytes && c.Trace == "" {
+		return errors.New("-bytes needs the object sizes of a -trace")
+	}
+	if c.MRC && (c.Stream || c.Lazy || len(c.YCSB) > 0) {
+		return errors.New("-mrc replays the keys in memory, it cannot be used with -stream, -lazy or -ycsb")
+	}
+	if c.MRC && c.Bytes {
+		return errors.New("-mrc sizes the caches in entries, as the stack distances of lru-stack, it cannot be used with -bytes")
+	}
+	if c.Backend != "" {
+		if _, err := parseBackend(c.Backend, c.BackendConcurrency, c.BackendWait, c.Seed); err != nil {
+			return err
+		}
+		if c.MRC {
+			return errors.New("-mrc only simulates the caches, it cannot be used with -backend")
+		}
+	}
+	if c.Warmup < 0 || c.Warmup >= 1 {
+		return fmt.Errorf("invalid -warmup %g: must be in [0, 1)", c.Warmup)
+	}
+	if c.Window != "" {
+		if _, err := parseWindow(c.Window); err != nil {
+			return err
+		}
+		if c.MRC {
+			return errors.New("-mrc only simulates the caches, it cannot be used with -window")
+		}
+	}
+	if c.MRCPoints < 0 {
+		return fmt.Errorf("invalid -mrc-points %d: must not be negative", c.MRCPoints)
+	}
+	if c.Count <= 0 {
+		return fmt.Errorf("invalid -count %d: must be positive", c.Count)
+	}
+	if !slices.Contains(formats, c.Format) {
+		return fmt.Errorf("invalid -format %q: must be one of %s", c.Format, strings.Join(formats, ", "))
+	}
+	if c.LatencyEvery < 0 {
+		return fmt.Errorf("invalid -latency %d: must not be negative", c.LatencyEvery)
+	}
+	if c.WorkloadMultiplier <= 0 {
+		return fmt.Errorf("invalid -workload %d: must be positive", c.WorkloadMultiplier)
+	}
+	return nil
+}
+
+func (c *Config) runOptions() runOptions {
+	var backend *Backend
+	if c.Backend != "" {
+		// validate parsed it already.
+		backend, _ = parseBackend(c.Backend, c.BackendConcurrency, c.BackendWait, c.Seed)
+	}
+	var window windowSpec
+	if c.Window != "" {
+		window, _ = parseWindow(c.Window)
+	}
+	return runOptions{
+		latencyEvery: c.LatencyEvery,
+		bytes:        c.Bytes,
+		backend:      backend,
+		warmup:       c.Warmup,
+		window:       window,
+	}
+}
+
+// stringList is a flag.Value of comma-separated strings.
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = nil
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// intList is a flag.Value of comma-separated integers, 1e5 is accepted.
+type intList []int
+
+func (l *intList) String() string {
+	s := make([]string, len(*l))
+	for i, v := range *l {
+		s[i] = strconv.Itoa(v)
+	}
+	return strings.Join(s, ",")
+}
+
+func (l *intList) Set(s string) error {
+	*l = nil
+	for _, v := range strings.Split(s, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || f != float64(int(f)) {
+			return fmt.Errorf("%q is not an integer", v)
+		}
+		*l = append(*l, int(f))
+	}
+	return nil
+}
+
+// floatList is a flag.Value of comma-separated floats.
+type floatList []float64
+
+func (l *floatList) String() string {
+	s := make([]string, len(*l))
+	for i, v := range *l {
+		s[i] = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return strings.Join(s, ",")
+}
+
+func (l *floatList) Set(s string) error {
+	*l = nil
+	for _, v := range strings.Split(s, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", v)
+		}
+		*l = append(*l, f)
+	}
+	return nil
+}
+
+// delimiter is a flag.Value of a single character.
+type delimiter rune
+
+func (d *delimiter) String() string {
+	return string(*d)
+}
+
+func (d *delimiter) Set(s string) error {
+	if s == "tab" || s == "\\t" {
+		s = "\t"
+	}
+	r := []rune(s)
+	if len(r) != 1 {
+		return fmt.Errorf("%q is not a single character", s)
+	}
+	*d = delimiter(r[0])
+	return nil
+}
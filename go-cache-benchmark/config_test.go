@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	tests := []struct {
+		args []string
+		// err is a part of the error, empty for none.
+		err string
+	}{
+		{args: nil},
+		{args: []string{"-caches", "sieve,shift", "-items", "1e3", "-ratios", "0.01,0.1", "-concurrency", "1,4"}},
+		{args: []string{"-gen", "zipf:0.9+scan:0.1,loop", "-loop-factor", "1.5"}},
+		{args: []string{"-lazy", "-gen", "uniform"}},
+		{args: []string{"-ycsb", "a,ycsb-f", "-ycsb-scan", "10"}},
+		{args: []string{"-trace", "trace.oracleGeneral.bin.zst", "-bytes", "-ratios", "0.0001"}},
+		{args: []string{"-trace", "trace.csv", "-csv-header", "-csv-key", "key", "-csv-op", "3", "-ops", "get"}},
+		{args: []string{"-mrc", "-mrc-points", "0"}},
+		{args: []string{"-backend", "exp:100us", "-backend-concurrency", "8", "-backend-wait", "spin", "-warmup", "0.1", "-window", "1e5"}},
+		{args: []string{"-format", "json", "-count", "3", "-latency", "100"}},
+
+		{args: []string{"extra"}, err: "unexpected arguments: extra"},
+		{args: []string{"-caches", "fifo"}, err: `unknown cache "fifo"`},
+		{args: []string{"-gen", ""}, err: "-gen must not be empty"},
+		{args: []string{"-gen", "pareto"}, err: `invalid -gen: unknown generator "pareto"`},
+		{args: []string{"-gen", "zipf+scan"}, err: "has no fraction"},
+		{args: []string{"-gen", "zipf:0"}, err: `invalid fraction "0"`},
+		{args: []string{"-lazy", "-gen", "scan"}, err: "only zipf and uniform can be drawn with -lazy"},
+		{args: []string{"-lazy", "-gen", "zipf:0.5+uniform:0.5"}, err: "only zipf and uniform can be drawn with -lazy"},
+		{args: []string{"-loop-factor", "0"}, err: "invalid -loop-factor 0"},
+		{args: []string{"-hotspot-size", "1.5"}, err: "invalid -hotspot-size 1.5"},
+		{args: []string{"-hotspot-prob", "-0.1"}, err: "invalid -hotspot-prob -0.1"},
+		{args: []string{"-hotspot-drift", "-1"}, err: "invalid -hotspot-drift -1"},
+		{args: []string{"-ycsb", "g"}, err: `invalid -ycsb: unknown YCSB workload "g"`},
+		{args: []string{"-ycsb-scan", "0"}, err: "invalid -ycsb-scan 0"},
+		{args: []string{"-ycsb", "a", "-lazy"}, err: "it cannot be used with -lazy"},
+		{args: []string{"-items", "1.5"}, err: `"1.5" is not an integer`},
+		{args: []string{"-items", "0"}, err: "invalid -items 0"},
+		{args: []string{"-alphas", "0"}, err: "invalid -alphas 0"},
+		{args: []string{"-ratios", "1.5"}, err: "invalid -ratios 1.5"},
+		{args: []string{"-items", "100", "-ratios", "0.001"}, err: "is less than one entry"},
+		{args: []string{"-concurrency", "0"}, err: "invalid -concurrency 0"},
+		{args: []string{"-trace", "trace.bin"}, err: "cannot detect the format of trace.bin"},
+		{args: []string{"-trace", "trace.bin", "-trace-format", "parquet"}, err: `invalid -trace-format "parquet"`},
+		{args: []string{"-trace", "trace.csv", "-csv-key", "key"}, err: "the trace has no header"},
+		{args: []string{"-trace", "trace.csv", "-csv-key", "0"}, err: "columns start at 1"},
+		{args: []string{"-trace", "trace.csv", "-ops", "get"}, err: "needs the operation column"},
+		{args: []string{"-trace", "trace.txt", "-trace-limit", "-1"}, err: "invalid -trace-limit -1"},
+		{args: []string{"-bytes"}, err: "-bytes needs the object sizes of a -trace"},
+		{args: []string{"-mrc", "-lazy"}, err: "-mrc replays the keys in memory"},
+		{args: []string{"-mrc", "-ycsb", "a"}, err: "-mrc replays the keys in memory"},
+		{args: []string{"-mrc", "-trace", "trace.txt", "-bytes"}, err: "it cannot be used with -bytes"},
+		{args: []string{"-backend", "fast"}, err: `invalid -backend "fast"`},
+		{args: []string{"-backend", "1ms", "-backend-wait", "busy"}, err: `invalid -backend-wait "busy"`},
+		{args: []string{"-mrc", "-backend", "1ms"}, err: "it cannot be used with -backend"},
+		{args: []string{"-warmup", "1"}, err: "invalid -warmup 1"},
+		{args: []string{"-window", "0"}, err: `invalid -window "0"`},
+		{args: []string{"-mrc", "-window", "1s"}, err: "it cannot be used with -window"},
+		{args: []string{"-mrc-points", "-1"}, err: "invalid -mrc-points -1"},
+		{args: []string{"-count", "0"}, err: "invalid -count 0"},
+		{args: []string{"-format", "xml"}, err: `invalid -format "xml"`},
+		{args: []string{"-latency", "-1"}, err: "invalid -latency -1"},
+		{args: []string{"-workload", "0"}, err: "invalid -workload 0"},
+	}
+	for _, tt := range tests {
+		_, err := parseConfig(tt.args, io.Discard)
+		if tt.err == "" && err != nil {
+			t.Errorf("%q: %v", tt.args, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%q: error %v, want %q", tt.args, err, tt.err)
+		}
+	}
+
+	if _, err := parseConfig([]string{"-h"}, io.Discard); !errors.Is(err, flag.ErrHelp) {
+		t.Errorf("-h: error %v, want %v", err, flag.ErrHelp)
+	}
+}
+
+func TestParseConfigValues(t *testing.T) {
+	c, err := parseConfig([]string{
+		"-caches", "sieve, shift", "-items", "1e3,2000", "-alphas", "0.8,1.2", "-ratios", "0.5",
+		"-backend", "1ms", "-backend-concurrency", "2", "-warmup", "0.25", "-window", "100ms",
+		"-trace", "trace.csv", "-csv-delimiter", "tab",
+	}, io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(c.Caches, []string{"sieve", "shift"}) || !slices.Equal(c.Items, []int{1000, 2000}) ||
+		!slices.Equal(c.Alphas, []float64{0.8, 1.2}) || !slices.Equal(c.CacheRatios, []float64{0.5}) {
+		t.Errorf("parsed %q, %v, %v and %v", c.Caches, c.Items, c.Alphas, c.CacheRatios)
+	}
+	if c.TraceOptions.CSV.Delimiter != '\t' {
+		t.Errorf("parsed the delimiter %q", c.TraceOptions.CSV.Delimiter)
+	}
+
+	o := c.runOptions()
+	if o.backend.String() != "1ms concurrency=2" || o.warmup != 0.25 || o.window.String() != "100ms" {
+		t.Errorf("run options %q, %g and %q", o.backend, o.warmup, o.window)
+	}
+	if o.warmupRequests(1000) != 250 {
+		t.Errorf("%d warmup requests of 1000, want 250", o.warmupRequests(1000))
+	}
+}
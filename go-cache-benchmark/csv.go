@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// CSVOptions maps the columns of a CSV trace to the fields of a Request.
+// A column is either a 1-based index or, with Header, the name of a column.
+// An empty column leaves the field unset, only Key is required.
+type CSVOptions struct {
+	Delimiter rune
+	Header    bool
+
+	KeyColumn  string
+	TimeColumn string
+	SizeColumn string
+	OpColumn   string
+
+	// Ops keeps only the requests whose operation is listed, empty keeps all.
+	Ops []string
+}
+
+func defaultCSVOptions() CSVOptions {
+	return CSVOptions{
+		Delimiter: ',',
+		KeyColumn: "1",
+	}
+}
+
+func (o *CSVOptions) validate() error {
+	if o.KeyColumn == "" {
+		return errors.New("the key column of the CSV trace is not set")
+	}
+	for _, col := range []string{o.KeyColumn, o.TimeColumn, o.SizeColumn, o.OpColumn} {
+		if col == "" {
+			continue
+		}
+		if i, err := strconv.Atoi(col); err == nil {
+			if i < 1 {
+				return fmt.Errorf("invalid CSV column %d: columns start at 1", i)
+			}
+		} else if !o.Header {
+			return fmt.Errorf("CSV column %q is a name, but the trace has no header", col)
+		}
+	}
+	if len(o.Ops) > 0 && o.OpColumn == "" {
+		return errors.New("filtering by operation needs the operation column of the CSV trace")
+	}
+	return nil
+}
+
+// CSVReader streams a CSV trace.
+type CSVReader struct {
+	f       io.Closer
+	r       *csv.Reader
+	options CSVOptions
+	ops     map[string]bool
+
+	// 0-based column indexes, -1 if absent, resolved on the first Read.
+	resolved            bool
+	key, time, size, op int
+}
+
+func NewCSVReader(r io.ReadCloser, options CSVOptions) *CSVReader {
+	cr := csv.NewReader(bufio.NewReaderSize(r, 1<<20))
+	cr.Comma = options.Delimiter
+	cr.FieldsPerRecord = -1
+	cr.ReuseRecord = true
+	cr.LazyQuotes = true
+
+	var ops map[string]bool
+	if len(options.Ops) > 0 {
+		ops = make(map[string]bool, len(options.Ops))
+		for _, op := range options.Ops {
+			ops[op] = true
+		}
+	}
+
+	return &CSVReader{
+		f:       r,
+		r:       cr,
+		options: options,
+		ops:     ops,
+	}
+}
+
+func (c *CSVReader) Read(r *Request) error {
+	if !c.resolved {
+		if err := c.resolve(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		record, err := c.r.Read()
+		if err != nil {
+			return err
+		}
+		line, _ := c.r.FieldPos(0)
+
+		field := func(i int) (string, error) {
+			if i >= len(record) {
+				return "", fmt.Errorf("line %d: missing column %d", line, i+1)
+			}
+			return strings.TrimSpace(record[i]), nil
+		}
+
+		*r = Request{NextAccess: -1}
+		if c.op >= 0 {
+			if r.Op, err = field(c.op); err != nil {
+				return err
+			}
+			if c.ops != nil && !c.ops[r.Op] {
+				continue
+			}
+		}
+		if r.Key, err = field(c.key); err != nil {
+			return err
+		}
+		if c.time >= 0 {
+			s, err := field(c.time)
+			if err != nil {
+				return err
+			}
+			if r.Timestamp, err = strconv.ParseUint(s, 10, 64); err != nil {
+				return fmt.Errorf("line %d: invalid timestamp %q", line, s)
+			}
+		}
+		if c.size >= 0 {
+			s, err := field(c.size)
+			if err != nil {
+				return err
+			}
+			size, err := strconv.ParseUint(s, 10, 32)
+			if err != nil {
+				return fmt.Errorf("line %d: invalid size %q", line, s)
+			}
+			r.Size = uint32(size)
+		}
+		return nil
+	}
+}
+
+// resolve reads the header, if any, and turns the columns into indexes.
+func (c *CSVReader) resolve() error {
+	var header []string
+	if c.options.Header {
+		record, err := c.r.Read()
+		if err != nil {
+			return fmt.Errorf("could not read the CSV header: %w", err)
+		}
+		header = make([]string, len(record))
+		for i, name := range record {
+			header[i] = strings.TrimSpace(name)
+		}
+	}
+
+	index := func(col string) (int, error) {
+		if col == "" {
+			return -1, nil
+		}
+		if i, err := strconv.Atoi(col); err == nil {
+			return i - 1, nil
+		}
+		for i, name := range header {
+			if name == col {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("no column %q in the CSV header %q", col, strings.Join(header, string(c.options.Delimiter)))
+	}
+
+	var err error
+	if c.key, err = index(c.options.KeyColumn); err != nil {
+		return err
+	}
+	if c.time, err = index(c.options.TimeColumn); err != nil {
+		return err
+	}
+	if c.size, err = index(c.options.SizeColumn); err != nil {
+		return err
+	}
+	if c.op, err = index(c.options.OpColumn); err != nil {
+		return err
+	}
+	c.resolved = true
+	return nil
+}
+
+func (c *CSVReader) Close() error {
+	return c.f.Close()
+}
+
+// TextReader streams a plain-text trace with one key per line.
+// Empty lines are skipped.
+type TextReader struct {
+	f io.Closer
+	s *bufio.Scanner
+}
+
+func NewTextReader(r io.ReadCloser) *TextReader {
+	s := bufio.NewScanner(bufio.NewReaderSize(r, 1<<20))
+	return &TextReader{f: r, s: s}
+}
+
+func (t *TextReader) Read(r *Request) error {
+	for t.s.Scan() {
+		key := strings.TrimSpace(t.s.Text())
+		if key == "" {
+			continue
+		}
+		*r = Request{Key: key, NextAccess: -1}
+		return nil
+	}
+	if err := t.s.Err(); err != nil {
+		return err
+	}
+	return io.EOF
+}
+
+func (t *TextReader) Close() error {
+	return t.f.Close()
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readAll reads the requests of r until io.EOF or another error.
+func readAll(r Reader) ([]Request, error) {
+	var requests []Request
+	for {
+		var req Request
+		if err := r.Read(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				return requests, nil
+			}
+			return requests, err
+		}
+		requests = append(requests, req)
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	// csv returns the options of a CSV trace with the given changes.
+	csv := func(change func(o *CSVOptions)) CSVOptions {
+		o := defaultCSVOptions()
+		change(&o)
+		return o
+	}
+	tests := []struct {
+		name    string
+		input   string
+		options CSVOptions
+		want    []Request
+		// err is a substring of the error, empty for none.
+		err string
+	}{
+		{
+			name:    "keys",
+			input:   "a\nb\n a \n",
+			options: defaultCSVOptions(),
+			want:    []Request{{Key: "a", NextAccess: -1}, {Key: "b", NextAccess: -1}, {Key: "a", NextAccess: -1}},
+		},
+		{
+			name:  "columns by index",
+			input: "10,GET,a,100\n20,SET,b,200\n",
+			options: csv(func(o *CSVOptions) {
+				o.TimeColumn, o.OpColumn, o.KeyColumn, o.SizeColumn = "1", "2", "3", "4"
+			}),
+			want: []Request{
+				{Timestamp: 10, Op: "GET", Key: "a", Size: 100, NextAccess: -1},
+				{Timestamp: 20, Op: "SET", Key: "b", Size: 200, NextAccess: -1},
+			},
+		},
+		{
+			name:  "columns by header name",
+			input: "time, key, size\n10, a, 100\n",
+			options: csv(func(o *CSVOptions) {
+				o.Header = true
+				o.TimeColumn, o.KeyColumn, o.SizeColumn = "time", "key", "size"
+			}),
+			want: []Request{{Timestamp: 10, Key: "a", Size: 100, NextAccess: -1}},
+		},
+		{
+			name:  "header and indexes",
+			input: "key,size\na,1\n",
+			options: csv(func(o *CSVOptions) {
+				o.Header = true
+				o.SizeColumn = "2"
+			}),
+			want: []Request{{Key: "a", Size: 1, NextAccess: -1}},
+		},
+		{
+			name:  "tab delimiter",
+			input: "a\t1\nb\t2\n",
+			options: csv(func(o *CSVOptions) {
+				o.Delimiter = '\t'
+				o.SizeColumn = "2"
+			}),
+			want: []Request{{Key: "a", Size: 1, NextAccess: -1}, {Key: "b", Size: 2, NextAccess: -1}},
+		},
+		{
+			name:  "ops filter",
+			input: "GET,a\nDEL,b\nSET,c\n",
+			options: csv(func(o *CSVOptions) {
+				o.OpColumn, o.KeyColumn = "1", "2"
+				o.Ops = []string{"GET", "SET"}
+			}),
+			want: []Request{{Op: "GET", Key: "a", NextAccess: -1}, {Op: "SET", Key: "c", NextAccess: -1}},
+		},
+		{
+			name:  "unknown header name",
+			input: "key,size\na,1\n",
+			options: csv(func(o *CSVOptions) {
+				o.Header = true
+				o.KeyColumn = "id"
+			}),
+			err: `no column "id" in the CSV header`,
+		},
+		{
+			name:  "missing column",
+			input: "a,1\nb\n",
+			options: csv(func(o *CSVOptions) {
+				o.SizeColumn = "2"
+			}),
+			want: []Request{{Key: "a", Size: 1, NextAccess: -1}},
+			err:  "line 2: missing column 2",
+		},
+		{
+			name:  "invalid size",
+			input: "a,1\nb,-2\n",
+			options: csv(func(o *CSVOptions) {
+				o.SizeColumn = "2"
+			}),
+			want: []Request{{Key: "a", Size: 1, NextAccess: -1}},
+			err:  `line 2: invalid size "-2"`,
+		},
+		{
+			name:  "invalid timestamp",
+			input: "x,a\n",
+			options: csv(func(o *CSVOptions) {
+				o.TimeColumn, o.KeyColumn = "1", "2"
+			}),
+			err: `line 1: invalid timestamp "x"`,
+		},
+		{
+			// an empty trace ends at once, even without its header.
+			name:  "empty with header",
+			input: "",
+			options: csv(func(o *CSVOptions) {
+				o.Header = true
+			}),
+		},
+	}
+	for _, tt := range tests {
+		r := NewCSVReader(io.NopCloser(strings.NewReader(tt.input)), tt.options)
+		got, err := readAll(r)
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: read %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCSVOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		options CSVOptions
+		err     string
+	}{
+		{"default", defaultCSVOptions(), ""},
+		{"no key", CSVOptions{Delimiter: ','}, "the key column of the CSV trace is not set"},
+		{"column 0", CSVOptions{KeyColumn: "0"}, "invalid CSV column 0: columns start at 1"},
+		{"name without header", CSVOptions{KeyColumn: "key"}, `CSV column "key" is a name, but the trace has no header`},
+		{"name with header", CSVOptions{KeyColumn: "key", Header: true}, ""},
+		{"ops without op column", CSVOptions{KeyColumn: "1", Ops: []string{"GET"}}, "filtering by operation needs the operation column"},
+	}
+	for _, tt := range tests {
+		err := tt.options.validate()
+		if tt.err == "" && err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		} else if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestTextReader(t *testing.T) {
+	r := NewTextReader(io.NopCloser(strings.NewReader("a\n\n  b  \r\nc")))
+	got, err := readAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Request{{Key: "a", NextAccess: -1}, {Key: "b", NextAccess: -1}, {Key: "c", NextAccess: -1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("read %+v, want %+v", got, want)
+	}
+}
@@ -3,16 +3,21 @@ package main
 import (
 	"fmt"
 	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/hey-kong/shift/go-cache-benchmark/zipf"
 )
 
 type ZipfGenerator struct {
 	gen *zipf.ZipfGenerator
+	// local draws without locking when the generator is split.
+	local *zipf.LocalZipfGenerator
 }
 
-func NewZipfGenerator(size uint64, theta float64) *ZipfGenerator {
-	src := rand.NewSource(19931203)
+func NewZipfGenerator(size uint64, theta float64, seed int64) *ZipfGenerator {
+	src := rand.NewSource(seed)
 	r := rand.New(src)
 	gen, err := zipf.NewZipfGenerator(r, 0, size, theta, false)
 
@@ -29,5 +34,278 @@ func (z *ZipfGenerator) Name() string {
 }
 
 func (z *ZipfGenerator) Next() string {
-	return fmt.Sprintf("%d", z.gen.Uint64())
+	if z.local != nil {
+		return strconv.FormatUint(z.local.Uint64(), 10)
+	}
+	return strconv.FormatUint(z.gen.Uint64(), 10)
+}
+
+func (z *ZipfGenerator) Split(seed int64) Generator {
+	return &ZipfGenerator{gen: z.gen, local: z.gen.Local(seed)}
+}
+
+// UniformGenerator draws keys uniformly from [0, size).
+type UniformGenerator struct {
+	r    *rand.Rand
+	size uint64
+}
+
+func NewUniformGenerator(size uint64, seed int64) *UniformGenerator {
+	return &UniformGenerator{
+		r:    rand.New(rand.NewSource(seed)),
+		size: size,
+	}
+}
+
+func (u *UniformGenerator) Name() string {
+	return "uniform"
+}
+
+func (u *UniformGenerator) Next() string {
+	return strconv.FormatUint(u.r.Uint64()%u.size, 10)
+}
+
+func (u *UniformGenerator) Split(seed int64) Generator {
+	return NewUniformGenerator(u.size, seed)
+}
+
+// ScanGenerator walks the keys [0, size) in order and starts over,
+// like a full table scan.
+type ScanGenerator struct {
+	size uint64
+	next uint64
+}
+
+func NewScanGenerator(size uint64) *ScanGenerator {
+	return &ScanGenerator{size: size}
+}
+
+func (s *ScanGenerator) Name() string {
+	return "scan"
+}
+
+func (s *ScanGenerator) Next() string {
+	key := s.next
+	s.next = (s.next + 1) % s.size
+	return strconv.FormatUint(key, 10)
+}
+
+// LoopGenerator repeats the keys [0, size) in order. With size slightly
+// larger than the cache, every request misses in LRU.
+type LoopGenerator struct {
+	ScanGenerator
+}
+
+func NewLoopGenerator(size uint64) *LoopGenerator {
+	return &LoopGenerator{ScanGenerator{size: max(size, 1)}}
+}
+
+func (l *LoopGenerator) Name() string {
+	return "loop"
+}
+
+// HotspotGenerator sends a fraction of the requests to a small set of
+// consecutive hot keys and the others uniformly to every key.
+// The hot set moves forward by one key every drift requests,
+// so the popular keys change over time.
+type HotspotGenerator struct {
+	r        *rand.Rand
+	size     uint64
+	hotSize  uint64
+	hotProb  float64
+	drift    int
+	offset   uint64
+	requests int
+}
+
+func NewHotspotGenerator(size, hotSize uint64, hotProb float64, drift int, seed int64) *HotspotGenerator {
+	return &HotspotGenerator{
+		r:       rand.New(rand.NewSource(seed)),
+		size:    size,
+		hotSize: min(max(hotSize, 1), size),
+		hotProb: hotProb,
+		drift:   drift,
+	}
+}
+
+func (h *HotspotGenerator) Name() string {
+	return "hotspot"
+}
+
+func (h *HotspotGenerator) Next() string {
+	h.requests++
+	if h.drift > 0 && h.requests%h.drift == 0 {
+		h.offset = (h.offset + 1) % h.size
+	}
+
+	var key uint64
+	if h.r.Float64() < h.hotProb {
+		key = (h.offset + h.r.Uint64()%h.hotSize) % h.size
+	} else {
+		key = h.r.Uint64() % h.size
+	}
+	return strconv.FormatUint(key, 10)
+}
+
+// phase is a generator replayed for a number of requests.
+type phase struct {
+	gen      Generator
+	requests int
+}
+
+// PhaseGenerator replays generators one after the other, e.g. Zipf keys
+// interrupted by a one-off scan. It starts over after the last phase.
+type PhaseGenerator struct {
+	name   string
+	phases []phase
+	cur    int
+	left   int
+}
+
+func NewPhaseGenerator(name string, phases []phase) *PhaseGenerator {
+	return &PhaseGenerator{
+		name:   name,
+		phases: phases,
+		left:   phases[0].requests,
+	}
+}
+
+func (p *PhaseGenerator) Name() string {
+	return p.name
+}
+
+func (p *PhaseGenerator) Next() string {
+	for p.left <= 0 {
+		p.cur = (p.cur + 1) % len(p.phases)
+		p.left = p.phases[p.cur].requests
+	}
+	p.left--
+	return p.phases[p.cur].gen.Next()
+}
+
+// GeneratorOptions holds the parameters of the generators but Zipf's alpha.
+type GeneratorOptions struct {
+	// LoopFactor is the length of a loop relative to the cache size.
+	LoopFactor float64
+	// HotspotSize is the size of the hot set relative to the number of keys.
+	HotspotSize float64
+	// HotspotProb is the fraction of requests sent to the hot set.
+	HotspotProb float64
+	// HotspotDrift is the number of requests between two moves of the hot set,
+	// relative to the number of keys, 0 never moves it.
+	HotspotDrift float64
+}
+
+func defaultGeneratorOptions() GeneratorOptions {
+	return GeneratorOptions{
+		LoopFactor:   1.1,
+		HotspotSize:  0.01,
+		HotspotProb:  0.9,
+		HotspotDrift: 0.01,
+	}
+}
+
+// describe returns the options of o that change the keys of the generator
+// of spec, such as "loop-factor=1.1", empty if none does.
+func (o GeneratorOptions) describe(spec string) string {
+	names, _, _ := parseGeneratorSpec(spec)
+	var options []string
+	if slices.Contains(names, "loop") {
+		options = append(options, fmt.Sprintf("loop-factor=%g", o.LoopFactor))
+	}
+	if slices.Contains(names, "hotspot") {
+		options = append(options, fmt.Sprintf("hotspot-size=%g hotspot-prob=%g hotspot-drift=%g",
+			o.HotspotSize, o.HotspotProb, o.HotspotDrift))
+	}
+	return strings.Join(options, " ")
+}
+
+var generatorNames = []string{"zipf", "uniform", "scan", "loop", "hotspot"}
+
+// parseGeneratorSpec splits a spec of phases, "zipf:0.45+scan:0.1+zipf:0.45",
+// into generator names and fractions of the requests. A single name has
+// the fraction 1.
+func parseGeneratorSpec(spec string) ([]string, []float64, error) {
+	var names []string
+	var fractions []float64
+	var sum float64
+	for _, p := range strings.Split(spec, "+") {
+		name, fraction, hasFraction := strings.Cut(strings.TrimSpace(p), ":")
+		if !slices.Contains(generatorNames, name) {
+			return nil, nil, fmt.Errorf("unknown generator %q in %q, available generators: %s",
+				name, spec, strings.Join(generatorNames, ", "))
+		}
+		f := 1.0
+		if hasFraction {
+			var err error
+			if f, err = strconv.ParseFloat(fraction, 64); err != nil || f <= 0 {
+				return nil, nil, fmt.Errorf("invalid fraction %q in %q: must be a positive number", fraction, spec)
+			}
+		} else if strings.Contains(spec, "+") {
+			return nil, nil, fmt.Errorf("phase %q in %q has no fraction", p, spec)
+		}
+		names = append(names, name)
+		fractions = append(fractions, f)
+		sum += f
+	}
+	for i := range fractions {
+		fractions[i] /= sum
+	}
+	return names, fractions, nil
+}
+
+// newGenerator builds the generator of a spec for items keys,
+// total requests and a cache of cacheSize entries.
+// The generators of a spec share a name and state across phases.
+func newGenerator(spec string, items, cacheSize, total int, alpha float64, seed int64, options GeneratorOptions) (Generator, error) {
+	names, fractions, err := parseGeneratorSpec(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	gens := make(map[string]Generator)
+	phases := make([]phase, len(names))
+	assigned := 0
+	for i, name := range names {
+		gen, ok := gens[name]
+		if !ok {
+			// every generator gets its own seed, derived from the master seed.
+			seed := seed + int64(len(gens))
+			switch name {
+			case "zipf":
+				gen = NewZipfGenerator(uint64(items), alpha, seed)
+			case "uniform":
+				gen = NewUniformGenerator(uint64(items), seed)
+			case "scan":
+				gen = NewScanGenerator(uint64(items))
+			case "loop":
+				loop := uint64(float64(cacheSize) * options.LoopFactor)
+				// a loop longer than the cache must not round down to fit in it.
+				if options.LoopFactor > 1 {
+					loop = max(loop, uint64(cacheSize)+1)
+				}
+				gen = NewLoopGenerator(loop)
+			case "hotspot":
+				gen = NewHotspotGenerator(uint64(items), uint64(float64(items)*options.HotspotSize),
+					options.HotspotProb, int(float64(items)*options.HotspotDrift), seed)
+			}
+			gens[name] = gen
+		}
+		phases[i] = phase{gen: gen, requests: int(float64(total) * fractions[i])}
+		assigned += phases[i].requests
+	}
+	// the requests rounded down go to the last phase, so that the phases
+	// add up to total.
+	phases[len(phases)-1].requests += total - assigned
+
+	if len(phases) == 1 {
+		return phases[0].gen, nil
+	}
+	return NewPhaseGenerator(spec, phases), nil
+}
+
+// usesAlpha reports whether Zipf's alpha matters to the generator of spec.
+func usesAlpha(spec string) bool {
+	names, _, _ := parseGeneratorSpec(spec)
+	return slices.Contains(names, "zipf")
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// draw returns the next n keys of gen.
+func draw(gen Generator, n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = gen.Next()
+	}
+	return keys
+}
+
+func TestGeneratorKeys(t *testing.T) {
+	options := defaultGeneratorOptions()
+	tests := []struct {
+		spec             string
+		items, cacheSize int
+		options          GeneratorOptions
+		name             string
+		distinct, maxKey int
+		prefix           string
+	}{
+		// zipf draws from [0, items], the most popular keys first.
+		{spec: "zipf", items: 100, cacheSize: 10, name: "zipf", maxKey: 100},
+		{spec: "uniform", items: 100, cacheSize: 10, name: "uniform", distinct: 100, maxKey: 99},
+		{spec: "scan", items: 5, cacheSize: 1, name: "scan", distinct: 5, maxKey: 4, prefix: "0 1 2 3 4 0 1"},
+		// 1.1 of 10 entries, a loop one longer than the cache.
+		{spec: "loop", items: 100, cacheSize: 10, name: "loop", distinct: 11, maxKey: 10, prefix: "0 1 2 3 4 5 6 7 8 9 10 0"},
+		// 1.1 of 5 entries rounds down to 5, the loop must still not fit.
+		{spec: "loop", items: 100, cacheSize: 5, name: "loop", distinct: 6, maxKey: 5},
+		{spec: "loop", items: 100, cacheSize: 1, name: "loop", distinct: 2, maxKey: 1},
+		// a loop shorter than the cache is asked for.
+		{spec: "loop", items: 100, cacheSize: 10, options: GeneratorOptions{LoopFactor: 0.5}, name: "loop", distinct: 5, maxKey: 4},
+		{spec: "hotspot", items: 1000, cacheSize: 10, name: "hotspot", maxKey: 999},
+		{spec: "zipf:1+scan:1", items: 100, cacheSize: 10, name: "zipf:1+scan:1", maxKey: 100},
+	}
+	for _, tt := range tests {
+		o := options
+		if tt.options.LoopFactor > 0 {
+			o.LoopFactor = tt.options.LoopFactor
+		}
+		gen, err := newGenerator(tt.spec, tt.items, tt.cacheSize, 10000, 0.99, 1, o)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.spec, err)
+		}
+		if gen.Name() != tt.name {
+			t.Errorf("%s: named %s, want %s", tt.spec, gen.Name(), tt.name)
+		}
+		keys := draw(gen, 10000)
+		if tt.prefix != "" {
+			prefix := strings.Fields(tt.prefix)
+			if got := strings.Join(keys[:len(prefix)], " "); got != tt.prefix {
+				t.Errorf("%s: drew %s, want %s", tt.spec, got, tt.prefix)
+			}
+		}
+		seen := make(map[string]bool)
+		for _, key := range keys {
+			k, err := strconv.Atoi(key)
+			if err != nil || k < 0 || k > tt.maxKey {
+				t.Fatalf("%s: drew %q, out of [0, %d]", tt.spec, key, tt.maxKey)
+			}
+			seen[key] = true
+		}
+		if tt.distinct > 0 && len(seen) != tt.distinct {
+			t.Errorf("%s: drew %d distinct keys, want %d", tt.spec, len(seen), tt.distinct)
+		}
+	}
+}
+
+func TestHotspotGenerator(t *testing.T) {
+	tests := []struct {
+		name    string
+		hotSize uint64
+		hotProb float64
+		drift   int
+		// hot is the least fraction of the keys in [0, hotSize).
+		hot float64
+	}{
+		{"all hot", 10, 1, 0, 1},
+		{"mostly hot", 10, 0.9, 0, 0.85},
+		{"no hot set", 10, 0, 0, 0},
+		// a drift of 1 moves the hot set away from [0, 10) after 10 requests.
+		{"drifting", 10, 1, 1, 0},
+	}
+	for _, tt := range tests {
+		h := NewHotspotGenerator(1000, tt.hotSize, tt.hotProb, tt.drift, 1)
+		hot := 0
+		const n = 10000
+		for _, key := range draw(h, n) {
+			if k, _ := strconv.Atoi(key); uint64(k) < tt.hotSize {
+				hot++
+			}
+		}
+		if f := float64(hot) / n; f < tt.hot || (tt.hot == 0 && f > 0.05) {
+			t.Errorf("%s: %.3f of the keys are in the first hot set, want at least %g", tt.name, f, tt.hot)
+		}
+	}
+}
+
+func TestPhaseGenerator(t *testing.T) {
+	tests := []struct {
+		spec  string
+		total int
+		// lengths are the requests of every phase.
+		lengths []int
+	}{
+		{"uniform:1+scan:1", 10, []int{5, 5}},
+		// the requests rounded down go to the last phase.
+		{"uniform:1+scan:1+uniform:1", 10, []int{3, 3, 4}},
+		{"uniform:0.45+scan:0.1+uniform:0.45", 1001, []int{450, 100, 451}},
+		{"scan:1+uniform:2", 7, []int{2, 5}},
+	}
+	for _, tt := range tests {
+		gen, err := newGenerator(tt.spec, 1000, 10, tt.total, 0.99, 1, defaultGeneratorOptions())
+		if err != nil {
+			t.Fatalf("%s: %v", tt.spec, err)
+		}
+		var lengths []int
+		for _, phase := range gen.(*PhaseGenerator).phases {
+			lengths = append(lengths, phase.requests)
+		}
+		if fmt.Sprint(lengths) != fmt.Sprint(tt.lengths) {
+			t.Errorf("%s: phases of %v requests, want %v", tt.spec, lengths, tt.lengths)
+		}
+
+		// the scan phases draw 0, 1, ... where they start, twice over.
+		keys := draw(gen, 2*tt.total)
+		start, next := 0, 0
+		for i := 0; i < 2*len(lengths); i++ {
+			phase := i % len(lengths)
+			if strings.HasPrefix(strings.Split(tt.spec, "+")[phase], "scan") {
+				for j := start; j < start+lengths[phase]; j++ {
+					if want := strconv.Itoa(next); keys[j] != want {
+						t.Fatalf("%s: key %d is %s, want %s of the scan", tt.spec, j, keys[j], want)
+					}
+					next++
+				}
+			}
+			start += lengths[phase]
+		}
+	}
+}
@@ -10,6 +10,7 @@ require (
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da
 	github.com/hashicorp/golang-lru/v2 v2.0.7
 	github.com/hey-kong/shift/golang-fifo v0.0.0-00010101000000-000000000000
+	github.com/klauspost/compress v1.17.9
 	github.com/maypok86/otter v0.0.0-20231222143008-a9479c80c78a
 	github.com/olekukonko/tablewriter v0.0.5
 	github.com/scalalang2/golang-fifo v0.1.3
@@ -0,0 +1,115 @@
+// Package histogram records latencies into logarithmic buckets, like
+// HdrHistogram: every power of two is split into linear sub-buckets, so
+// recording is a few instructions and quantiles are within about 3%
+// of the recorded values, from nanoseconds to hours.
+package histogram
+
+import (
+	"math/bits"
+	"time"
+)
+
+const (
+	// subBucketBits is the number of bits of precision of a bucket,
+	// 2^subBucketBits sub-buckets per power of two.
+	subBucketBits  = 5
+	subBucketCount = 1 << subBucketBits
+	bucketCount    = (64 - subBucketBits + 1) * subBucketCount
+)
+
+// Histogram counts values in logarithmic buckets. It is not safe for
+// concurrent use: give every goroutine its own and Merge them.
+type Histogram struct {
+	counts [bucketCount]uint64
+	total  uint64
+	sum    uint64
+	max    uint64
+}
+
+func New() *Histogram {
+	return &Histogram{}
+}
+
+// index returns the bucket of v.
+func index(v uint64) int {
+	if v < subBucketCount {
+		return int(v)
+	}
+	// v>>shift keeps the subBucketBits+1 most significant bits of v.
+	shift := bits.Len64(v) - subBucketBits - 1
+	return (shift+1)<<subBucketBits + int(v>>shift) - subBucketCount
+}
+
+// highest returns the highest value of bucket i.
+func highest(i int) uint64 {
+	if i < subBucketCount {
+		return uint64(i)
+	}
+	shift := i>>subBucketBits - 1
+	lowest := uint64(i&(subBucketCount-1)+subBucketCount) << shift
+	return lowest + 1<<shift - 1
+}
+
+// Record adds the value v.
+func (h *Histogram) Record(v uint64) {
+	h.counts[index(v)]++
+	h.total++
+	h.sum += v
+	if v > h.max {
+		h.max = v
+	}
+}
+
+// RecordDuration adds the duration d in nanoseconds.
+func (h *Histogram) RecordDuration(d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	h.Record(uint64(d))
+}
+
+// Merge adds the values of o to h.
+func (h *Histogram) Merge(o *Histogram) {
+	for i, c := range o.counts {
+		h.counts[i] += c
+	}
+	h.total += o.total
+	h.sum += o.sum
+	h.max = max(h.max, o.max)
+}
+
+// Count returns the number of values recorded.
+func (h *Histogram) Count() uint64 {
+	return h.total
+}
+
+// Max returns the largest value recorded.
+func (h *Histogram) Max() uint64 {
+	return h.max
+}
+
+// Mean returns the exact mean of the values recorded, 0 if h is empty.
+func (h *Histogram) Mean() float64 {
+	if h.total == 0 {
+		return 0
+	}
+	return float64(h.sum) / float64(h.total)
+}
+
+// Quantile returns the value below which a fraction q of the values fall,
+// rounded up to the highest value of its bucket. It returns 0 if h is empty.
+func (h *Histogram) Quantile(q float64) uint64 {
+	if h.total == 0 {
+		return 0
+	}
+	rank := uint64(q*float64(h.total) + 0.5)
+	rank = min(max(rank, 1), h.total)
+	var seen uint64
+	for i, c := range h.counts {
+		seen += c
+		if seen >= rank {
+			return min(highest(i), h.max)
+		}
+	}
+	return h.max
+}
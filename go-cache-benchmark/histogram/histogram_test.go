@@ -0,0 +1,122 @@
+package histogram
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBuckets(t *testing.T) {
+	tests := []struct {
+		v       uint64
+		index   int
+		highest uint64
+	}{
+		// values below 2^subBucketBits have a bucket each.
+		{0, 0, 0},
+		{1, 1, 1},
+		{31, 31, 31},
+		{32, 32, 32},
+		{63, 63, 63},
+		// from 64, a bucket holds 2 values, from 128 4 values, and so on.
+		{64, 64, 65},
+		{65, 64, 65},
+		{66, 65, 67},
+		{127, 95, 127},
+		{128, 96, 131},
+		{131, 96, 131},
+		{132, 97, 135},
+		{math.MaxUint64, bucketCount - 1, math.MaxUint64},
+	}
+	for _, tt := range tests {
+		if got := index(tt.v); got != tt.index {
+			t.Errorf("index(%d) = %d, want %d", tt.v, got, tt.index)
+		}
+		if got := highest(tt.index); got != tt.highest {
+			t.Errorf("highest(%d) = %d, want %d", tt.index, got, tt.highest)
+		}
+	}
+
+	// the buckets are contiguous: the value after the highest of a bucket
+	// is in the next one.
+	for i := 0; i < bucketCount-1; i++ {
+		h := highest(i)
+		if index(h) != i || index(h+1) != i+1 {
+			t.Fatalf("bucket %d ends at %d, index(%d) = %d, index(%d) = %d", i, h, h, index(h), h+1, index(h+1))
+		}
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []uint64
+		q      float64
+		want   uint64
+	}{
+		{"empty", nil, 0.5, 0},
+		{"single", []uint64{1000}, 0.5, 1000},
+		{"min", []uint64{3, 1, 2}, 0, 1},
+		{"p50 exact", seq(1, 100), 0.5, 50},
+		// 99 shares its bucket with 98, the highest of the bucket is 99.
+		{"p99 exact", seq(1, 100), 0.99, 99},
+		// 98 is rounded up to the highest of its bucket.
+		{"rounded up", []uint64{98, 98, 200}, 0.5, 99},
+		// the highest of the bucket of 100 is 101, above the max.
+		{"capped at max", seq(1, 100), 1, 100},
+		// 999 is in the bucket of 992 to 1007, the outlier is not reached.
+		{"p999 outlier", append(seq(1, 999), 1e9), 0.999, 1007},
+	}
+	for _, tt := range tests {
+		h := New()
+		for _, v := range tt.values {
+			h.Record(v)
+		}
+		if got := h.Quantile(tt.q); got != tt.want {
+			t.Errorf("%s: Quantile(%g) = %d, want %d", tt.name, tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestQuantileError(t *testing.T) {
+	// the quantiles are within 1/2^subBucketBits of the recorded values.
+	for _, v := range []uint64{100, 12345, 1e6, 987654321, 1 << 40} {
+		h := New()
+		h.Record(v)
+		h.Record(2 * v)
+		got := h.Quantile(0.5)
+		if got < v || float64(got-v) > float64(v)/subBucketCount {
+			t.Errorf("Quantile(0.5) of %d and %d = %d, want within %d of %d", v, 2*v, got, v/subBucketCount, v)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	a, b, all := New(), New(), New()
+	for v := uint64(0); v < 1000; v++ {
+		if v%3 == 0 {
+			a.Record(v * v)
+		} else {
+			b.Record(v * v)
+		}
+		all.Record(v * v)
+	}
+	a.Merge(b)
+	if a.Count() != all.Count() || a.Max() != all.Max() || a.Mean() != all.Mean() {
+		t.Fatalf("merged count, max, mean = %d, %d, %g, want %d, %d, %g",
+			a.Count(), a.Max(), a.Mean(), all.Count(), all.Max(), all.Mean())
+	}
+	for _, q := range []float64{0, 0.5, 0.9, 0.99, 0.999, 1} {
+		if got, want := a.Quantile(q), all.Quantile(q); got != want {
+			t.Errorf("merged Quantile(%g) = %d, want %d", q, got, want)
+		}
+	}
+}
+
+// seq returns the values from first to last.
+func seq(first, last uint64) []uint64 {
+	var values []uint64
+	for v := first; v <= last; v++ {
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/hey-kong/shift/go-cache-benchmark/cache"
+	"github.com/hey-kong/shift/go-cache-benchmark/histogram"
+)
+
+// Latency holds the latencies, in nanoseconds, of the sampled operations of a run.
+type Latency struct {
+	GetHit  *histogram.Histogram
+	GetMiss *histogram.Histogram
+	Set     *histogram.Histogram
+	// EndToEnd times every request, from the Get to the Set of a miss
+	// including the backend, nil without a backend.
+	EndToEnd *histogram.Histogram
+
+	// summaries are the summaries of the histograms, by operation, of a
+	// Latency read back from JSON. Its histograms are nil.
+	summaries map[string]LatencySummary
+}
+
+func newLatency(backend bool) *Latency {
+	l := &Latency{
+		GetHit:  histogram.New(),
+		GetMiss: histogram.New(),
+		Set:     histogram.New(),
+	}
+	if backend {
+		l.EndToEnd = histogram.New()
+	}
+	return l
+}
+
+func (l *Latency) merge(o *Latency) {
+	l.GetHit.Merge(o.GetHit)
+	l.GetMiss.Merge(o.GetMiss)
+	l.Set.Merge(o.Set)
+	if l.EndToEnd != nil {
+		l.EndToEnd.Merge(o.EndToEnd)
+	}
+}
+
+// latencyRecorder replays the requests of one worker. It times one request
+// in every, the Get and the Set of a miss, so that reading the clock does
+// not slow down the untimed requests. Every is 0 when no request is timed.
+// With a backend, every request is also timed end to end, see accessBackend.
+type latencyRecorder struct {
+	every   int
+	n       int
+	latency *Latency
+	// bytes sets the objects with their size in caches with a capacity
+	// in bytes.
+	bytes bool
+	// backend is waited for on every miss, with latencies drawn from rng.
+	backend *Backend
+	rng     *rand.Rand
+}
+
+// newLatencyRecorders returns a recorder per worker. Their histograms stay
+// reachable when the cache is dropped, retained does not count them.
+func newLatencyRecorders(concurrency int, options runOptions) []*latencyRecorder {
+	recorders := make([]*latencyRecorder, concurrency)
+	for i := range recorders {
+		recorders[i] = &latencyRecorder{every: options.latencyEvery, bytes: options.bytes}
+		if options.latencyEvery > 0 || options.backend != nil {
+			recorders[i].latency = newLatency(options.backend != nil)
+		}
+		if options.backend != nil {
+			recorders[i].backend = options.backend
+			recorders[i].rng = options.backend.rand(i)
+		}
+	}
+	return recorders
+}
+
+// mergeLatency merges the latencies of every recorder, nil if none was recorded.
+func mergeLatency(recorders []*latencyRecorder) *Latency {
+	if recorders[0].latency == nil {
+		return nil
+	}
+	l := newLatency(recorders[0].backend != nil)
+	for _, r := range recorders {
+		l.merge(r.latency)
+	}
+	return l
+}
+
+// sample reports whether the next operation is timed.
+func (r *latencyRecorder) sample() bool {
+	if r.every <= 0 {
+		return false
+	}
+	r.n++
+	if r.n < r.every {
+		return false
+	}
+	r.n = 0
+	return true
+}
+
+// access gets key from c and sets it on a miss, and reports whether it hit.
+// Size is the size of the object, 0 if unknown.
+func (r *latencyRecorder) access(c cache.Cache, key string, size uint32) bool {
+	if r.backend != nil {
+		return r.accessBackend(c, key, size)
+	}
+	if !r.sample() {
+		if c.Get(key) {
+			return true
+		}
+		r.fill(c, key, size)
+		return false
+	}
+
+	start := time.Now()
+	hit := c.Get(key)
+	got := time.Now()
+	if hit {
+		r.latency.GetHit.RecordDuration(got.Sub(start))
+		return true
+	}
+	r.latency.GetMiss.RecordDuration(got.Sub(start))
+	r.fill(c, key, size)
+	r.latency.Set.RecordDuration(time.Since(got))
+	return false
+}
+
+// warm gets key from c and sets it on a miss, without timing nor waiting
+// for the backend, to fill c before the measured requests.
+func (r *latencyRecorder) warm(c cache.Cache, key string, size uint32) {
+	if !c.Get(key) {
+		r.fill(c, key, size)
+	}
+}
+
+// accessBackend is access with a backend: a miss waits for the backend
+// before setting key, and every request is timed end to end.
+func (r *latencyRecorder) accessBackend(c cache.Cache, key string, size uint32) bool {
+	sampled := r.sample()
+	start := time.Now()
+	hit := c.Get(key)
+	if sampled {
+		if hit {
+			r.latency.GetHit.RecordDuration(time.Since(start))
+		} else {
+			r.latency.GetMiss.RecordDuration(time.Since(start))
+		}
+	}
+	if !hit {
+		r.backend.fetch(r.rng)
+		fetched := time.Now()
+		r.fill(c, key, size)
+		if sampled {
+			r.latency.Set.RecordDuration(time.Since(fetched))
+		}
+	}
+	r.latency.EndToEnd.RecordDuration(time.Since(start))
+	return hit
+}
+
+// set writes key through c. With a backend, the write waits for the
+// backend like a miss before setting key, and is timed end to end.
+func (r *latencyRecorder) set(c cache.Cache, key string) {
+	sampled := r.sample()
+	if !sampled && r.backend == nil {
+		c.Set(key)
+		return
+	}
+	start := time.Now()
+	if r.backend != nil {
+		r.backend.fetch(r.rng)
+	}
+	written := time.Now()
+	c.Set(key)
+	if sampled {
+		r.latency.Set.RecordDuration(time.Since(written))
+	}
+	if r.backend != nil {
+		r.latency.EndToEnd.RecordDuration(time.Since(start))
+	}
+}
+
+// fill sets key in c after a miss, with its size if c has a capacity in bytes.
+func (r *latencyRecorder) fill(c cache.Cache, key string, size uint32) {
+	if r.bytes && size > 0 {
+		if s, ok := c.(cache.Sized); ok {
+			s.SetSize(key, int64(size))
+			return
+		}
+	}
+	c.Set(key)
+}
@@ -1,121 +1,709 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"sync"
 	"time"
 
 	"github.com/hey-kong/shift/go-cache-benchmark/cache"
+	"github.com/hey-kong/shift/go-cache-benchmark/zipf"
 )
 
-const workloadMultiplier = 15
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "compare":
+			os.Exit(compareMain(os.Args[2:]))
+		case "report":
+			os.Exit(reportMain(os.Args[2:]))
+		}
+	}
+
+	config, err := parseConfig(os.Args[1:], os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go-cache-benchmark: %v\n", err)
+		os.Exit(2)
+	}
 
-type NewCacheFunc func(size int) cache.Cache
+	if config.List {
+		for _, name := range cacheNames() {
+			fmt.Println(name)
+		}
+		return
+	}
 
-func main() {
-	zipfAlphas := []float64{0.99}
-	items := []int{1e5 * 5}
-	concurrencies := []int{1, 2, 4, 8, 16}
-	cacheSizeMultiplier := []float64{0.001, 0.01, 0.1}
-	caches := []NewCacheFunc{
-		cache.NewSieve,
-		cache.NewShift,
-		cache.NewS3FIFO,
-		cache.NewLRU,
-		cache.NewTwoQueue,
-		cache.NewLRUGroupCache,
-		cache.NewTinyLFU,
-		cache.NewSLRU,
-		cache.NewS4LRU,
-		cache.NewClock,
-		cache.NewFreeLRUSynced,
-		cache.NewFreeLRUSharded,
-	}
-
-	for _, itemSize := range items {
-		for _, multiplier := range cacheSizeMultiplier {
-			for _, curr := range concurrencies {
-				for _, alpha := range zipfAlphas {
-					runBenchmark(itemSize, multiplier, alpha, caches, curr)
+	caches, err := lookupCaches(config.Caches)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go-cache-benchmark: %v\n", err)
+		os.Exit(2)
+	}
+
+	out, err := openOutput(config.Output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go-cache-benchmark: %v\n", err)
+		os.Exit(1)
+	}
+	results, err := newResultWriter(config.Format, out, newMetadata(os.Args[1:], config.Seed))
+	if err == nil {
+		err = benchmark(config, caches, results)
+		err = errors.Join(err, results.Close())
+	}
+	err = errors.Join(err, out.Close())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go-cache-benchmark: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// openOutput opens the file at path for the results, stdout if path is empty.
+func openOutput(path string) (io.WriteCloser, error) {
+	if path == "" {
+		return nopCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// benchmark runs every benchmark of config and writes them to results.
+func benchmark(config *Config, caches []NewCacheFunc, results ResultWriter) error {
+	options := config.runOptions()
+	// write runs every benchmark Count times, to tell noise from changes.
+	write := func(run func() (*Benchmark, error)) error {
+		for i := 0; i < config.Count; i++ {
+			b, err := run()
+			if err != nil {
+				return err
+			}
+			if err := results.Write(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// mrc writes the miss ratio curve of a workload.
+	mrc := func(w *workload) error {
+		benchmarks := runMRC(w, mrcRatios(config.CacheRatios, config.MRCPoints), caches, options)
+		if t, ok := results.(*tableWriter); ok {
+			writeMRC(t.w, benchmarks)
+			return nil
+		}
+		for _, b := range benchmarks {
+			if err := results.Write(b); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if config.Trace != "" {
+		load := loadTrace
+		if config.Stream {
+			load = scanTrace
+		}
+		w, err := load(config.Trace, config.TraceOptions, config.TraceLimit)
+		if err != nil {
+			return err
+		}
+		if config.Bytes {
+			if w.bytes == 0 {
+				return fmt.Errorf("trace %s has no object sizes, -bytes needs them", config.Trace)
+			}
+			if caches, err = lookupByteCaches(config.Caches, w.meanSize()); err != nil {
+				return err
+			}
+		}
+		if config.MRC {
+			return mrc(w)
+		}
+		for _, multiplier := range config.CacheRatios {
+			if int(float64(w.items)*multiplier) < 1 {
+				fmt.Fprintf(os.Stderr, "skipping -ratios %g: %g of the %d keys of %s is less than one entry\n", multiplier, multiplier, w.items, w.name)
+				continue
+			}
+			for _, curr := range config.Concurrencies {
+				err := write(func() (*Benchmark, error) {
+					return runBenchmark(w, multiplier, caches, curr, options)
+				})
+				if err != nil {
+					return err
 				}
 			}
 		}
+		return nil
 	}
+
+	if len(config.YCSB) > 0 {
+		for _, name := range config.YCSB {
+			y, _ := lookupYCSBWorkload(name)
+			for _, itemSize := range config.Items {
+				for _, multiplier := range config.CacheRatios {
+					for _, curr := range config.Concurrencies {
+						for _, alpha := range config.Alphas {
+							run := generateYCSB(y, itemSize, itemSize*config.WorkloadMultiplier, alpha, config.YCSBScanLength, config.Seed)
+							err := write(func() (*Benchmark, error) {
+								return runYCSBBenchmark(run, multiplier, caches, curr, options), nil
+							})
+							if err != nil {
+								return err
+							}
+						}
+					}
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, spec := range config.Generators {
+		// alpha only matters to zipf, run the other generators once.
+		alphas := config.Alphas
+		if !usesAlpha(spec) {
+			alphas = []float64{0}
+		}
+		if config.MRC {
+			for _, itemSize := range config.Items {
+				for _, alpha := range alphas {
+					// a loop is as long as the largest cache.
+					cacheSize := int(float64(itemSize) * slices.Max(config.CacheRatios))
+					total := itemSize * config.WorkloadMultiplier
+					gen, err := newGenerator(spec, itemSize, cacheSize, total, alpha, config.Seed, config.GeneratorOptions)
+					if err != nil {
+						return err
+					}
+					w := generateWorkload(gen, itemSize, total)
+					w.alpha = alpha
+					w.genOptions = config.GeneratorOptions.describe(spec)
+					if err := mrc(w); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+		for _, itemSize := range config.Items {
+			for _, multiplier := range config.CacheRatios {
+				for _, curr := range config.Concurrencies {
+					for _, alpha := range alphas {
+						total := itemSize * config.WorkloadMultiplier
+						cacheSize := int(float64(itemSize) * multiplier)
+						gen, err := newGenerator(spec, itemSize, cacheSize, total, alpha, config.Seed, config.GeneratorOptions)
+						if err != nil {
+							return err
+						}
+						var w *workload
+						if config.Lazy {
+							// validate only allows generators that are Splitters.
+							w = &workload{name: gen.Name(), items: itemSize, requests: total, split: gen.(Splitter), seed: config.Seed}
+						} else {
+							w = generateWorkload(gen, itemSize, total)
+						}
+						w.alpha = alpha
+						w.genOptions = config.GeneratorOptions.describe(spec)
+						// keys drawn in every goroutine are not those drawn in advance.
+						if config.Lazy && w.genOptions != "" {
+							w.genOptions += " lazy"
+						} else if config.Lazy {
+							w.genOptions = "lazy"
+						}
+						err = write(func() (*Benchmark, error) {
+							return runBenchmark(w, multiplier, caches, curr, options)
+						})
+						if err != nil {
+							return err
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// runOptions are the settings of a run that do not change the workload.
+type runOptions struct {
+	// latencyEvery times one in latencyEvery operations, 0 times none.
+	latencyEvery int
+	// bytes sets the objects with their size in caches with a capacity
+	// in bytes.
+	bytes bool
+	// backend is waited for on every miss, nil for none.
+	backend *Backend
+	// warmup is the fraction of the requests replayed before the
+	// measured ones, to fill the caches.
+	warmup float64
+	// window splits the measured requests into windows.
+	window windowSpec
 }
 
-func runBenchmark(itemSize int, cacheMultiplier float64, zipfAlpha float64, caches []NewCacheFunc, concurrency int) {
+// warmupRequests is the number of requests of the warmup of a workload.
+func (o runOptions) warmupRequests(requests int) int {
+	return int(float64(requests) * o.warmup)
+}
+
+// workload is the request sequence replayed against every cache of a benchmark.
+type workload struct {
+	name  string
+	alpha float64
+	trace bool
+	// genOptions are the generator options that change the keys, see
+	// GeneratorOptions.describe.
+	genOptions string
+	// items is the number of distinct keys, cache sizes are relative to it.
+	items    int
+	requests int
+	keys     []string
+	// sizes are the object sizes of keys, nil if the trace has none.
+	sizes []uint32
+	// bytes is the sum of the sizes of the distinct objects.
+	bytes int64
+	// next is the next access of every request, computed on first use,
+	// possibly by several simulations at once.
+	next     []int64
+	nextOnce sync.Once
+
+	// split draws the keys in every goroutine instead of keys,
+	// goroutine k from the seed derived from seed and k.
+	split Splitter
+	seed  int64
+
+	// stream replays the trace from the file instead of keys.
+	stream        bool
+	path          string
+	options       TraceOptions
+	limit         int
+	hasNextAccess bool
+}
+
+// meanSize is the mean size of the distinct objects, 0 if unknown.
+func (w *workload) meanSize() float64 {
+	return float64(w.bytes) / float64(w.items)
+}
+
+// capacity is the capacity in bytes of caches of size entries with -bytes,
+// as lookupByteCaches sizes them, 0 without.
+func (w *workload) capacity(size int, options runOptions) int64 {
+	if !options.bytes {
+		return 0
+	}
+	return int64(float64(size) * w.meanSize())
+}
+
+// generateWorkload draws total keys from gen in advance to not taint the QPS.
+func generateWorkload(gen Generator, items, total int) *workload {
+	keys := make([]string, 0, total)
+	for i := 0; i < total; i++ {
+		keys = append(keys, gen.Next())
+	}
+	return &workload{
+		name:     gen.Name(),
+		items:    items,
+		requests: total,
+		keys:     keys,
+	}
+}
+
+// loadTrace reads up to limit requests of a trace into memory, 0 reads it all.
+func loadTrace(path string, options TraceOptions, limit int) (*workload, error) {
+	r, err := NewTraceReader(path, options)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	w := &workload{name: r.Name(), trace: true}
+	seen := make(map[string]struct{})
+	var req Request
+	for limit <= 0 || len(w.keys) < limit {
+		if err := r.Read(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("could not read %s: %w", path, err)
+		}
+		w.keys = append(w.keys, req.Key)
+		w.next = append(w.next, req.NextAccess)
+		w.sizes = append(w.sizes, req.Size)
+		if _, ok := seen[req.Key]; !ok {
+			seen[req.Key] = struct{}{}
+			w.bytes += int64(req.Size)
+		}
+	}
+	if len(w.keys) == 0 {
+		return nil, fmt.Errorf("trace %s is empty", path)
+	}
+	w.items = len(seen)
+	w.requests = len(w.keys)
+	if !r.HasNextAccess() {
+		w.next = nil
+	}
+	if w.bytes == 0 {
+		w.sizes = nil
+	}
+	return w, nil
+}
+
+// scanTrace prepares a trace to be streamed from the file by every run,
+// with bounded memory. It only counts the distinct keys of the trace.
+func scanTrace(path string, options TraceOptions, limit int) (*workload, error) {
+	r, err := openTrace(path, options)
+	if err != nil {
+		return nil, err
+	}
+	_, hasNextAccess := r.(*OracleGeneralReader)
+	if limit > 0 {
+		r = &limitedReader{Reader: r, n: limit}
+	}
+	p := NewPrefetchReader(r, prefetchBatchSize, prefetchDepth)
+	defer p.Close()
+
+	w := &workload{
+		name:          filepath.Base(path),
+		trace:         true,
+		stream:        true,
+		path:          path,
+		options:       options,
+		limit:         limit,
+		hasNextAccess: hasNextAccess,
+	}
+	seen := make(map[string]struct{})
+	var req Request
+	for {
+		if err := p.Read(&req); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("could not read %s: %w", path, err)
+		}
+		w.requests++
+		if _, ok := seen[req.Key]; !ok {
+			seen[req.Key] = struct{}{}
+			w.bytes += int64(req.Size)
+		}
+	}
+	if w.requests == 0 {
+		return nil, fmt.Errorf("trace %s is empty", path)
+	}
+	w.items = len(seen)
+	return w, nil
+}
+
+func (w *workload) nextAccess() []int64 {
+	w.nextOnce.Do(func() {
+		w.next = cache.NextAccess(w.keys)
+	})
+	return w.next
+}
+
+// runBenchmark runs every cache on w, it returns the first error reading
+// a streamed trace.
+func runBenchmark(w *workload, cacheMultiplier float64, caches []NewCacheFunc, concurrency int, options runOptions) (*Benchmark, error) {
 	b := &Benchmark{
-		ItemSize:            itemSize,
+		ItemSize:            w.items,
+		Workloads:           w.requests,
 		CacheSizeMultiplier: cacheMultiplier,
-		ZipfAlpha:           zipfAlpha,
+		ZipfAlpha:           w.alpha,
+		ByteCapacity:        options.bytes,
+		Backend:             options.backend.String(),
+		Warmup:              options.warmupRequests(w.requests),
+		Window:              options.window.String(),
 		Concurrency:         concurrency,
 		Results:             make([]*BenchmarkResult, 0),
 	}
+	if w.trace {
+		b.Trace = w.name
+	} else {
+		b.Generator = w.name
+		b.GeneratorOptions = w.genOptions
+	}
 
 	for _, newCache := range caches {
-		b.Results = append(b.Results, run(newCache, itemSize, cacheMultiplier, zipfAlpha, concurrency))
+		if w.stream {
+			r, err := runStream(newCache, w, cacheMultiplier, concurrency, options)
+			if err != nil {
+				return nil, err
+			}
+			if r != nil {
+				b.Results = append(b.Results, r)
+			}
+			continue
+		}
+		if r := run(newCache, w, cacheMultiplier, concurrency, options); r != nil {
+			b.Results = append(b.Results, r)
+		}
 	}
 
-	b.WriteToConsole()
+	b.sortResults()
+	return b, nil
 }
 
-func run(newCache NewCacheFunc, itemSize int, cacheSizeMultiplier float64, zipfAlpha float64, concurrency int) *BenchmarkResult {
-	gen := NewZipfGenerator(uint64(itemSize), zipfAlpha)
+func run(newCache NewCacheFunc, w *workload, cacheSizeMultiplier float64, concurrency int, options runOptions) *BenchmarkResult {
+	// worker k replays keys[k], keys[k+concurrency], ... after the warmup.
+	keys := w.keys
+	warm := options.warmupRequests(w.requests)
 
-	total := itemSize * workloadMultiplier
-	each := total / concurrency
+	cacheSize := int(float64(w.items) * cacheSizeMultiplier)
+	// the state of the workload belongs to no cache, it is allocated before
+	// the cache: the generators of the lazy workers and the recorders. The
+	// next accesses of an oracle stay in w, reachable with or without c.
 
-	// create keys in advance to not taint the QPS
-	keys := make([][]string, concurrency)
-	for i := 0; i < concurrency; i++ {
-		keys[i] = make([]string, 0, each)
-		for j := 0; j < each; j++ {
-			keys[i] = append(keys[i], gen.Next())
+	// lazy workers draw their keys from generators of their own,
+	// and the warmup from yet another one.
+	gens := make([]Generator, concurrency+1)
+	if w.split != nil {
+		for k := range gens {
+			gens[k] = w.split.Split(zipf.DeriveSeed(w.seed, k))
 		}
 	}
-
-	cacheSize := int(float64(itemSize) * cacheSizeMultiplier)
+	recorders := newLatencyRecorders(concurrency, options)
+	windows := newWindowRecorders(concurrency, options.window)
 	c := newCache(cacheSize)
-	defer c.Close()
+
+	// offline policies replay the whole sequence in order on one goroutine.
+	if o, ok := c.(cache.Oracle); ok {
+		if w.split != nil {
+			fmt.Fprintf(os.Stderr, "skipping %s: keys drawn with -lazy give no next access\n", c.Name())
+			c.Close()
+			return nil
+		}
+		next := w.nextAccess()
+		recorders, windows = recorders[:1], windows[:1]
+		for i, key := range keys[:warm] {
+			o.SetNextAccess(next[i])
+			recorders[0].warm(o, key, w.size(i))
+		}
+		start := time.Now()
+		startWindows(windows, start)
+		var n counts
+		for i := warm; i < len(keys); i++ {
+			o.SetNextAccess(next[i])
+			size := w.size(i)
+			hit := recorders[0].access(o, keys[i], size)
+			n.add(hit, size)
+			windows[0].add(int64(i-warm), hit)
+		}
+		elapsed := time.Since(start)
+		windows[0].finish()
+		result := n.result(c.Name())
+		result.Duration = elapsed
+		result.Memory = retained(c)
+		result.Entries = cacheSize
+		result.Capacity = w.capacity(cacheSize, options)
+		result.Latency = mergeLatency(recorders)
+		result.Windows = mergeWindows(windows, elapsed)
+		return result
+	}
+
+	key := func(gen Generator, j int) string {
+		if gen != nil {
+			return gen.Next()
+		}
+		return keys[j]
+	}
+
+	// the warmup fills the cache on one goroutine, before the timer.
+	for j := 0; j < warm; j++ {
+		recorders[0].warm(c, key(gens[concurrency], j), w.size(j))
+	}
 
 	start := time.Now()
-	bench := func(c cache.Cache, gen *ZipfGenerator) (int64, int64) {
+	startWindows(windows, start)
+	bench := func(c cache.Cache) counts {
 		var wg sync.WaitGroup
-		hits := make([]int64, concurrency)
-		misses := make([]int64, concurrency)
+		n := make([]counts, concurrency)
 
 		for i := 0; i < concurrency; i++ {
 			wg.Add(1)
 			go func(k int) {
-				for j := 0; j < each; j++ {
-					key := keys[k][j]
-					if c.Get(key) {
-						hits[k]++
-					} else {
-						misses[k]++
-						c.Set(key)
-					}
+				for j := warm + k; j < w.requests; j += concurrency {
+					size := w.size(j)
+					hit := recorders[k].access(c, key(gens[k], j), size)
+					n[k].add(hit, size)
+					windows[k].add(int64(j-warm), hit)
 				}
+				windows[k].finish()
 				wg.Done()
 			}(i)
 		}
 
 		wg.Wait()
-		var totalHits, totalMisses int64
+		var total counts
 		for i := 0; i < concurrency; i++ {
-			totalHits += hits[i]
-			totalMisses += misses[i]
+			total.merge(n[i])
 		}
-		return totalHits, totalMisses
+		return total
 	}
 
-	hits, misses := bench(c, gen)
+	n := bench(c)
 	elapsed := time.Since(start)
-	keys = nil
 
-	return &BenchmarkResult{
-		CacheName: c.Name(),
-		Duration:  elapsed,
-		Hits:      hits,
-		Misses:    misses,
+	result := n.result(c.Name())
+	result.Duration = elapsed
+	result.Memory = retained(c)
+	result.Entries = cacheSize
+	result.Capacity = w.capacity(cacheSize, options)
+	result.Latency = mergeLatency(recorders)
+	result.Windows = mergeWindows(windows, elapsed)
+	return result
+}
+
+// size is the size of the object of request i, 0 if unknown.
+func (w *workload) size(i int) uint32 {
+	if w.sizes == nil {
+		return 0
+	}
+	return w.sizes[i]
+}
+
+const (
+	prefetchBatchSize = 4096
+	prefetchDepth     = 16
+)
+
+// runStream replays a trace from its file while a background goroutine
+// decodes the next batches. Workers take whole batches, so requests of a
+// batch are replayed in order by one goroutine.
+//
+// It returns the first error reading the trace, the workers stop once the
+// prefetcher stops on it.
+func runStream(newCache NewCacheFunc, w *workload, cacheSizeMultiplier float64, concurrency int, options runOptions) (*BenchmarkResult, error) {
+	warm := options.warmupRequests(w.requests)
+	cacheSize := int(float64(w.items) * cacheSizeMultiplier)
+	recorders := newLatencyRecorders(concurrency, options)
+	windows := newWindowRecorders(concurrency, options.window)
+	c := newCache(cacheSize)
+
+	o, oracle := c.(cache.Oracle)
+	if oracle && !w.hasNextAccess {
+		fmt.Fprintf(os.Stderr, "skipping %s: streaming %s gives no next access, replay it without -stream\n", c.Name(), w.name)
+		c.Close()
+		return nil, nil
+	}
+
+	r, err := openTrace(w.path, w.options)
+	if err != nil {
+		c.Close()
+		return nil, fmt.Errorf("could not open trace %s: %w", w.path, err)
+	}
+	if w.limit > 0 {
+		r = &limitedReader{Reader: r, n: w.limit}
+	}
+	p := NewPrefetchReader(r, prefetchBatchSize, prefetchDepth)
+
+	if oracle {
+		concurrency = 1
+		recorders, windows = recorders[:1], windows[:1]
+	}
+
+	// the warmup fills the cache on one goroutine, before the timer. The
+	// rest of its last batch is replayed first by worker 0.
+	var pending *RequestBatch
+	for warmed := 0; warmed < warm; {
+		b, err := p.NextBatch()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			p.Close()
+			c.Close()
+			return nil, fmt.Errorf("could not read trace %s: %w", w.path, err)
+		}
+		m := min(len(b.Requests), warm-warmed)
+		for _, req := range b.Requests[:m] {
+			if oracle {
+				o.SetNextAccess(req.NextAccess)
+			}
+			recorders[0].warm(c, req.Key, req.Size)
+		}
+		warmed += m
+		if m < len(b.Requests) {
+			pending = b
+		} else {
+			p.Release(b)
+		}
+	}
+
+	start := time.Now()
+	startWindows(windows, start)
+	var wg sync.WaitGroup
+	n := make([]counts, concurrency)
+	// errs are the errors that stopped the workers, the prefetcher returns
+	// its error to all of them.
+	errs := make([]error, concurrency)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			defer windows[k].finish()
+			var b *RequestBatch
+			if k == 0 {
+				b = pending
+			}
+			for {
+				if b == nil {
+					var err error
+					b, err = p.NextBatch()
+					if errors.Is(err, io.EOF) {
+						return
+					}
+					if err != nil {
+						errs[k] = err
+						return
+					}
+				}
+				for i, req := range b.Requests {
+					index := b.Offset + int64(i) - int64(warm)
+					if index < 0 {
+						continue
+					}
+					if oracle {
+						o.SetNextAccess(req.NextAccess)
+					}
+					hit := recorders[k].access(c, req.Key, req.Size)
+					n[k].add(hit, req.Size)
+					windows[k].add(index, hit)
+				}
+				p.Release(b)
+				b = nil
+			}
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+	// release the prefetched batches before measuring the cache.
+	p.Close()
+	for _, err := range errs {
+		if err != nil {
+			c.Close()
+			return nil, fmt.Errorf("could not read trace %s: %w", w.path, err)
+		}
+	}
+
+	var total counts
+	for i := 0; i < concurrency; i++ {
+		total.merge(n[i])
 	}
+	result := total.result(c.Name())
+	result.Duration = elapsed
+	result.Memory = retained(c)
+	result.Entries = cacheSize
+	result.Capacity = w.capacity(cacheSize, options)
+	result.Latency = mergeLatency(recorders)
+	result.Windows = mergeWindows(windows, elapsed)
+	return result, nil
 }
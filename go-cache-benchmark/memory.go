@@ -0,0 +1,49 @@
+package main
+
+import (
+	"runtime"
+	"slices"
+
+	"github.com/hey-kong/shift/go-cache-benchmark/cache"
+)
+
+// heapSamples is the number of forced GCs liveHeap reads the heap after.
+const heapSamples = 5
+
+// liveHeap returns the median of the bytes of live heap objects after
+// several forced GCs. A single GC can leave garbage of the background
+// goroutines, of otter or of the runtime, that the next ones collect.
+func liveHeap() uint64 {
+	samples := make([]uint64, heapSamples)
+	var m runtime.MemStats
+	for i := range samples {
+		runtime.GC()
+		runtime.ReadMemStats(&m)
+		samples[i] = m.HeapAlloc
+	}
+	slices.Sort(samples)
+	return samples[len(samples)/2]
+}
+
+// retained closes c and returns the bytes of heap only it retained,
+// -1 if they could not be measured.
+//
+// It reads the live heap with c still reachable, then again once c is
+// closed and dropped: the difference is the entries and metadata of the
+// cache, such as ghost tables. The workload stays reachable in both, so
+// key strings shared with the pre-generated workload are not counted,
+// keys read from a stream or drawn with -lazy are. The caller must not
+// use c afterwards, or it stays reachable.
+func retained(c cache.Cache) int64 {
+	reachable := liveHeap()
+	runtime.KeepAlive(c)
+	// Close stops the goroutines of the cache, which would keep it reachable.
+	c.Close()
+	dropped := liveHeap()
+	// the heap can still grow in between, from goroutines of another
+	// package for instance.
+	if dropped > reachable {
+		return -1
+	}
+	return int64(reachable - dropped)
+}
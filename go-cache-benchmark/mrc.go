@@ -0,0 +1,254 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hey-kong/shift/go-cache-benchmark/cache"
+	"github.com/olekukonko/tablewriter"
+)
+
+// lruStackName is the exact LRU computed from stack distances.
+const lruStackName = "lru-stack"
+
+// mrcRatios returns points cache ratios spaced logarithmically between the
+// smallest and the largest of ratios, or ratios itself if points is 0.
+func mrcRatios(ratios []float64, points int) []float64 {
+	lo, hi := ratios[0], ratios[0]
+	for _, r := range ratios {
+		lo = min(lo, r)
+		hi = max(hi, r)
+	}
+	if points <= 0 || lo == hi {
+		sorted := append([]float64(nil), ratios...)
+		sort.Float64s(sorted)
+		return sorted
+	}
+	if points == 1 {
+		return []float64{hi}
+	}
+
+	grid := make([]float64, points)
+	step := math.Log(hi/lo) / float64(points-1)
+	for i := range grid {
+		grid[i] = lo * math.Exp(step*float64(i))
+	}
+	grid[points-1] = hi
+	return grid
+}
+
+// stackDistances returns, for every d, the number of requests of keys from
+// the index from whose LRU stack distance is d: the number of distinct keys
+// requested since the previous request to the same key. First requests are
+// counted in cold.
+//
+// A request hits in an LRU cache of size c if and only if its stack
+// distance is smaller than c, so a single pass gives the miss ratio of every
+// size. It keeps a 1 at the last request of every key in a Fenwick tree,
+// the distance is the number of 1s after the previous request to the key.
+func stackDistances(keys []string, from int) (distances []int64, cold int64) {
+	tree := make([]int32, len(keys)+1)
+	add := func(i int, v int32) {
+		for i++; i < len(tree); i += i & -i {
+			tree[i] += v
+		}
+	}
+	// sum returns the number of 1s in [0, i].
+	sum := func(i int) int {
+		s := 0
+		for i++; i > 0; i -= i & -i {
+			s += int(tree[i])
+		}
+		return s
+	}
+
+	last := make(map[string]int)
+	for t, key := range keys {
+		p, ok := last[key]
+		if !ok {
+			if t >= from {
+				cold++
+			}
+		} else {
+			if t >= from {
+				d := sum(t-1) - sum(p)
+				for len(distances) <= d {
+					distances = append(distances, 0)
+				}
+				distances[d]++
+			}
+			add(p, -1)
+		}
+		add(t, 1)
+		last[key] = t
+	}
+	return distances, cold
+}
+
+// lruMisses returns the misses of an LRU cache of every size, from the
+// stack distances of its requests.
+func lruMisses(distances []int64, cold int64, sizes []int) []int64 {
+	// hits[c] is the number of requests with a distance smaller than c.
+	hits := make([]int64, len(distances)+1)
+	for d, n := range distances {
+		hits[d+1] = hits[d] + n
+	}
+	var total int64
+	for _, n := range distances {
+		total += n
+	}
+
+	misses := make([]int64, len(sizes))
+	for i, size := range sizes {
+		misses[i] = cold + total - hits[min(size, len(distances))]
+	}
+	return misses
+}
+
+// simulate replays every request of w in order on one goroutine, and counts
+// those after the warmup. It does not measure memory, so that simulations
+// can run in parallel.
+func simulate(newCache NewCacheFunc, w *workload, cacheSize int, options runOptions) *BenchmarkResult {
+	c := newCache(cacheSize)
+	defer c.Close()
+
+	o, oracle := c.(cache.Oracle)
+	var next []int64
+	if oracle {
+		next = w.nextAccess()
+	}
+
+	warm := options.warmupRequests(w.requests)
+	recorder := newLatencyRecorders(1, runOptions{bytes: options.bytes})[0]
+	start := time.Now()
+	var n counts
+	for i, key := range w.keys {
+		if oracle {
+			o.SetNextAccess(next[i])
+		}
+		size := w.size(i)
+		if i < warm {
+			recorder.warm(c, key, size)
+			continue
+		}
+		n.add(recorder.access(c, key, size), size)
+	}
+	result := n.result(c.Name())
+	result.Duration = time.Since(start)
+	result.Entries = cacheSize
+	return result
+}
+
+// runMRC simulates every cache at every ratio of the number of keys of w,
+// one simulation per core at a time, and returns a benchmark per ratio.
+// The benchmarks also have the exact LRU, from the stack distances of w.
+func runMRC(w *workload, ratios []float64, caches []NewCacheFunc, options runOptions) []*Benchmark {
+	warm := options.warmupRequests(w.requests)
+	var sizes []int
+	var benchmarks []*Benchmark
+	for _, ratio := range ratios {
+		size := int(float64(w.items) * ratio)
+		// several ratios round to the same number of entries on small workloads.
+		if size <= 0 || (len(sizes) > 0 && sizes[len(sizes)-1] == size) {
+			continue
+		}
+		sizes = append(sizes, size)
+		b := &Benchmark{
+			ItemSize:            w.items,
+			Workloads:           w.requests,
+			CacheSizeMultiplier: ratio,
+			ZipfAlpha:           w.alpha,
+			ByteCapacity:        options.bytes,
+			Warmup:              warm,
+			Concurrency:         1,
+			Results:             make([]*BenchmarkResult, len(caches)+1),
+		}
+		if w.trace {
+			b.Trace = w.name
+		} else {
+			b.Generator = w.name
+			b.GeneratorOptions = w.genOptions
+		}
+		benchmarks = append(benchmarks, b)
+	}
+
+	type job struct {
+		benchmark, cache int
+	}
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < runtime.GOMAXPROCS(0); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				benchmarks[j.benchmark].Results[j.cache] = simulate(caches[j.cache], w, sizes[j.benchmark], options)
+			}
+		}()
+	}
+
+	go func() {
+		for i := range benchmarks {
+			for c := range caches {
+				jobs <- job{benchmark: i, cache: c}
+			}
+		}
+		close(jobs)
+	}()
+
+	start := time.Now()
+	distances, cold := stackDistances(w.keys, warm)
+	misses := lruMisses(distances, cold, sizes)
+	elapsed := time.Since(start)
+	for i, b := range benchmarks {
+		b.Results[len(caches)] = &BenchmarkResult{
+			CacheName: lruStackName,
+			Duration:  elapsed,
+			Hits:      int64(w.requests-warm) - misses[i],
+			Misses:    misses[i],
+			Entries:   sizes[i],
+		}
+	}
+
+	wg.Wait()
+	return benchmarks
+}
+
+// writeMRC writes the miss ratio of every cache at every size of benchmarks,
+// the results of runMRC, as a table with a row per size.
+func writeMRC(w io.Writer, benchmarks []*Benchmark) {
+	if len(benchmarks) == 0 {
+		return
+	}
+	first := benchmarks[0]
+	fmt.Fprintf(w, "%s, itemSize=%d, workloads=%d", first.workloadName(), first.ItemSize, first.Workloads)
+	if first.ZipfAlpha > 0 {
+		fmt.Fprintf(w, ", zipf's alpha=%.2f", first.ZipfAlpha)
+	}
+	fmt.Fprintf(w, "\n\n")
+
+	headers := []string{"CacheSize", "Entries"}
+	for _, r := range first.Results {
+		headers = append(headers, r.CacheName)
+	}
+	table := tablewriter.NewWriter(w)
+	for _, b := range benchmarks {
+		row := []string{
+			fmt.Sprintf("%.4f%%", b.CacheSizeMultiplier*100),
+			fmt.Sprintf("%d", b.Results[0].Entries),
+		}
+		for _, r := range b.Results {
+			row = append(row, fmt.Sprintf("%.2f%%", 100-r.hitRate()))
+		}
+		table.Append(row)
+	}
+	table.SetHeader(headers)
+	table.SetBorder(false)
+	table.Render()
+	fmt.Fprintf(w, "\n\n")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestStackDistances(t *testing.T) {
+	tests := []struct {
+		keys      string
+		from      int
+		distances []int64
+		cold      int64
+	}{
+		{"", 0, nil, 0},
+		{"aaaa", 0, []int64{3}, 1},
+		{"abab", 0, []int64{0, 2}, 2},
+		// a at 2 is 1 away, b at 4 and a at 5 are 2 away.
+		{"abacba", 0, []int64{0, 1, 2}, 3},
+		// the requests before from are not counted, but set the distances.
+		{"abacba", 3, []int64{0, 0, 2}, 1},
+		{"abacba", 6, nil, 0},
+	}
+	for _, tt := range tests {
+		keys := make([]string, len(tt.keys))
+		for i, k := range tt.keys {
+			keys[i] = string(k)
+		}
+		distances, cold := stackDistances(keys, tt.from)
+		if !reflect.DeepEqual(distances, tt.distances) || cold != tt.cold {
+			t.Errorf("stackDistances(%q, %d) = %v, %d, want %v, %d", tt.keys, tt.from, distances, cold, tt.distances, tt.cold)
+		}
+	}
+}
+
+// bruteForceLRU returns the misses, after the first from requests, of an
+// LRU cache of size entries, most recent key last.
+func bruteForceLRU(keys []string, from, size int) int64 {
+	var lru []string
+	var misses int64
+	for t, key := range keys {
+		if i := slices.Index(lru, key); i >= 0 {
+			lru = slices.Delete(lru, i, i+1)
+		} else {
+			if t >= from {
+				misses++
+			}
+			if len(lru) == size {
+				lru = lru[1:]
+			}
+		}
+		lru = append(lru, key)
+	}
+	return misses
+}
+
+func TestLRUMisses(t *testing.T) {
+	sizes := []int{1, 2, 3, 5, 8, 13, 40, 100}
+	tests := []struct {
+		keys, requests, from int
+		seed                 int64
+	}{
+		{1, 10, 0, 1},
+		{5, 100, 0, 2},
+		{20, 500, 0, 3},
+		{20, 500, 250, 4},
+		{50, 1000, 100, 5},
+		{200, 2000, 0, 6},
+	}
+	for _, tt := range tests {
+		r := rand.New(rand.NewSource(tt.seed))
+		keys := make([]string, tt.requests)
+		for i := range keys {
+			// skewed, so that every size hits a different number of times.
+			keys[i] = fmt.Sprint(r.Intn(tt.keys) * r.Intn(tt.keys) % tt.keys)
+		}
+
+		distances, cold := stackDistances(keys, tt.from)
+		misses := lruMisses(distances, cold, sizes)
+		for i, size := range sizes {
+			if want := bruteForceLRU(keys, tt.from, size); misses[i] != want {
+				t.Errorf("%d keys, %d requests from %d: %d misses at size %d, want %d",
+					tt.keys, tt.requests, tt.from, misses[i], size, want)
+			}
+		}
+	}
+}
+
+func TestMRCRatios(t *testing.T) {
+	tests := []struct {
+		ratios []float64
+		points int
+		want   []float64
+	}{
+		{[]float64{0.1, 0.01}, 0, []float64{0.01, 0.1}},
+		{[]float64{0.1}, 5, []float64{0.1}},
+		{[]float64{0.01, 0.1}, 1, []float64{0.1}},
+		{[]float64{0.001, 0.1}, 3, []float64{0.001, 0.01, 0.1}},
+		{[]float64{0.1, 0.001, 0.01}, 2, []float64{0.001, 0.1}},
+	}
+	for _, tt := range tests {
+		got := mrcRatios(tt.ratios, tt.points)
+		if len(got) != len(tt.want) {
+			t.Errorf("mrcRatios(%v, %d) = %v, want %v", tt.ratios, tt.points, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if d := got[i] - tt.want[i]; d > 1e-12 || d < -1e-12 {
+				t.Errorf("mrcRatios(%v, %d) = %v, want %v", tt.ratios, tt.points, got, tt.want)
+				break
+			}
+		}
+	}
+}
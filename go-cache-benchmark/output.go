@@ -0,0 +1,391 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hey-kong/shift/go-cache-benchmark/histogram"
+)
+
+const (
+	FormatTable    = "table"
+	FormatJSON     = "json"
+	FormatCSV      = "csv"
+	FormatMarkdown = "markdown"
+)
+
+var formats = []string{FormatTable, FormatJSON, FormatCSV, FormatMarkdown}
+
+// Metadata describes the environment of a run, so that results of different
+// machines and revisions can be told apart.
+type Metadata struct {
+	Time        time.Time `json:"time"`
+	Args        []string  `json:"args"`
+	Seed        int64     `json:"seed"`
+	GoVersion   string    `json:"go_version"`
+	GOOS        string    `json:"goos"`
+	GOARCH      string    `json:"goarch"`
+	NumCPU      int       `json:"num_cpu"`
+	GOMAXPROCS  int       `json:"gomaxprocs"`
+	GitRevision string    `json:"git_revision"`
+}
+
+func newMetadata(args []string, seed int64) Metadata {
+	return Metadata{
+		Time:        time.Now().UTC(),
+		Args:        args,
+		Seed:        seed,
+		GoVersion:   runtime.Version(),
+		GOOS:        runtime.GOOS,
+		GOARCH:      runtime.GOARCH,
+		NumCPU:      runtime.NumCPU(),
+		GOMAXPROCS:  runtime.GOMAXPROCS(0),
+		GitRevision: gitRevision(),
+	}
+}
+
+// gitRevision returns the revision the binary was built from, with a
+// "-dirty" suffix for local changes. go run does not stamp it, so it falls
+// back on asking git. It is empty outside of a git checkout.
+func gitRevision() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		var revision, modified string
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.modified":
+				modified = s.Value
+			}
+		}
+		if revision != "" {
+			if modified == "true" {
+				revision += "-dirty"
+			}
+			return revision
+		}
+	}
+
+	out, err := exec.Command("git", "rev-parse", "HEAD").Output()
+	if err != nil {
+		return ""
+	}
+	revision := strings.TrimSpace(string(out))
+	if err := exec.Command("git", "diff", "--quiet", "HEAD").Run(); err != nil {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// Report is the JSON document of a run.
+type Report struct {
+	Metadata   Metadata     `json:"metadata"`
+	Benchmarks []*Benchmark `json:"benchmarks"`
+}
+
+// ResultWriter writes the benchmarks of a run as they complete.
+type ResultWriter interface {
+	Write(b *Benchmark) error
+	// Close writes what remains once every benchmark is written.
+	Close() error
+}
+
+func newResultWriter(format string, w io.Writer, metadata Metadata) (ResultWriter, error) {
+	switch format {
+	case FormatTable:
+		return &tableWriter{w: w}, nil
+	case FormatJSON:
+		return &jsonWriter{w: w, report: Report{Metadata: metadata, Benchmarks: []*Benchmark{}}}, nil
+	case FormatCSV:
+		return &csvWriter{w: csv.NewWriter(w), metadata: metadata}, nil
+	case FormatMarkdown:
+		return &markdownWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown format %q, available formats: %s", format, strings.Join(formats, ", "))
+}
+
+type tableWriter struct {
+	w io.Writer
+}
+
+func (t *tableWriter) Write(b *Benchmark) error {
+	b.WriteTable(t.w)
+	return nil
+}
+
+func (t *tableWriter) Close() error {
+	return nil
+}
+
+// jsonWriter writes a single Report once every benchmark is done.
+type jsonWriter struct {
+	w      io.Writer
+	report Report
+}
+
+func (j *jsonWriter) Write(b *Benchmark) error {
+	j.report.Benchmarks = append(j.report.Benchmarks, b)
+	return nil
+}
+
+func (j *jsonWriter) Close() error {
+	enc := json.NewEncoder(j.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(j.report)
+}
+
+// csvWriter writes a row per cache and benchmark, with the metadata
+// repeated on every row.
+type csvWriter struct {
+	w        *csv.Writer
+	metadata Metadata
+	header   bool
+}
+
+var csvHeader = []string{
+	"time", "seed", "go_version", "gomaxprocs", "git_revision",
+	"workload", "item_size", "workloads", "cache_ratio", "alpha", "concurrency",
+	"cache", "hit_rate", "qps", "bytes_per_entry", "hits", "misses",
+	"byte_hit_rate", "byte_hits", "byte_misses", "duration_ns",
+	"get_hit_p50_ns", "get_hit_p99_ns", "get_hit_p999_ns",
+	"get_miss_p50_ns", "get_miss_p99_ns", "get_miss_p999_ns",
+	"set_p50_ns", "set_p99_ns", "set_p999_ns",
+	"backend", "end_to_end_mean_ns", "end_to_end_p50_ns", "end_to_end_p99_ns", "end_to_end_p999_ns",
+	"byte_capacity", "memory_percent_of_capacity",
+}
+
+func (c *csvWriter) Write(b *Benchmark) error {
+	if !c.header {
+		c.header = true
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+
+	m := c.metadata
+	for _, r := range b.Results {
+		// an empty memory was not measured, or is in the other unit.
+		var bytesPerEntry, memoryPerCapacity string
+		if r.hasMemory() && r.Capacity > 0 {
+			memoryPerCapacity = formatFloat(r.memoryPerCapacity())
+		} else if r.hasMemory() {
+			bytesPerEntry = formatFloat(r.memoryPerCapacity())
+		}
+		row := []string{
+			m.Time.Format(time.RFC3339),
+			strconv.FormatInt(m.Seed, 10),
+			m.GoVersion,
+			strconv.Itoa(m.GOMAXPROCS),
+			m.GitRevision,
+			b.workloadName(),
+			strconv.Itoa(b.ItemSize),
+			strconv.Itoa(b.Workloads),
+			formatFloat(b.CacheSizeMultiplier),
+			formatFloat(b.ZipfAlpha),
+			strconv.Itoa(b.Concurrency),
+			r.CacheName,
+			formatFloat(r.hitRate()),
+			formatFloat(r.qps(r.operations())),
+			bytesPerEntry,
+			strconv.FormatInt(r.Hits, 10),
+			strconv.FormatInt(r.Misses, 10),
+		}
+		if r.hasBytes() {
+			row = append(row, formatFloat(r.byteHitRate()), strconv.FormatInt(r.ByteHits, 10), strconv.FormatInt(r.ByteMisses, 10))
+		} else {
+			row = append(row, "", "", "")
+		}
+		row = append(row, strconv.FormatInt(r.Duration.Nanoseconds(), 10))
+		for _, op := range latencyOps[:3] {
+			if l, ok := r.Latency.summary(op); ok {
+				row = append(row, formatUint(l.P50), formatUint(l.P99), formatUint(l.P999))
+			} else {
+				row = append(row, "", "", "")
+			}
+		}
+		row = append(row, b.Backend)
+		if e2e, ok := r.endToEnd(); ok {
+			row = append(row, formatFloat(e2e.Mean), formatUint(e2e.P50), formatUint(e2e.P99), formatUint(e2e.P999))
+		} else {
+			row = append(row, "", "", "", "")
+		}
+		row = append(row, strconv.FormatBool(b.ByteCapacity), memoryPerCapacity)
+		if err := c.w.Write(row); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// markdownWriter writes a table per benchmark, ready to paste in a README.
+type markdownWriter struct {
+	w io.Writer
+}
+
+func (m *markdownWriter) Write(b *Benchmark) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "**%s**, itemSize=%d, workloads=%d, cacheSize=%.2f%%", b.workloadName(), b.ItemSize, b.Workloads, b.CacheSizeMultiplier*100)
+	if b.ByteCapacity {
+		sb.WriteString(" of bytes")
+	}
+	if b.ZipfAlpha > 0 {
+		fmt.Fprintf(&sb, ", zipf's alpha=%.2f", b.ZipfAlpha)
+	}
+	fmt.Fprintf(&sb, ", concurrency=%d", b.Concurrency)
+	if b.Backend != "" {
+		fmt.Fprintf(&sb, ", backend=%s", b.Backend)
+	}
+	sb.WriteString("\n\n")
+	hasBytes := false
+	for _, r := range b.Results {
+		hasBytes = hasBytes || r.hasBytes()
+	}
+	sb.WriteString("| Cache | Hit rate ")
+	if hasBytes {
+		sb.WriteString("| Byte hit rate | Bytes saved ")
+	}
+	fmt.Fprintf(&sb, "| QPS | %s ", b.memoryHeader())
+	if b.Backend != "" {
+		sb.WriteString("| Mean latency | P99 latency ")
+	}
+	sb.WriteString("|\n|-------|---------:")
+	if hasBytes {
+		sb.WriteString("|--------------:|------------:")
+	}
+	sb.WriteString("|----:|" + strings.Repeat("-", len(b.memoryHeader())+1) + ":")
+	if b.Backend != "" {
+		sb.WriteString("|-------------:|------------:")
+	}
+	sb.WriteString("|\n")
+	for _, r := range b.Results {
+		fmt.Fprintf(&sb, "| %s | %.2f%% ", r.CacheName, r.hitRate())
+		if r.hasBytes() {
+			fmt.Fprintf(&sb, "| %.2f%% | %s ", r.byteHitRate(), formatBytes(r.ByteHits))
+		} else if hasBytes {
+			sb.WriteString("| - | - ")
+		}
+		fmt.Fprintf(&sb, "| %.f | %s ", r.qps(r.operations()), r.formatMemory())
+		if e2e, ok := r.endToEnd(); ok {
+			fmt.Fprintf(&sb, "| %s | %s ", formatNanos(e2e.Mean), formatNanos(float64(e2e.P99)))
+		} else if b.Backend != "" {
+			sb.WriteString("| - | - ")
+		}
+		sb.WriteString("|\n")
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(m.w, sb.String())
+	return err
+}
+
+func (m *markdownWriter) Close() error {
+	return nil
+}
+
+// workloadName is the trace or the generator of b.
+func (b *Benchmark) workloadName() string {
+	if b.Trace != "" {
+		return b.Trace
+	}
+	return b.Generator
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func formatUint(n uint64) string {
+	return strconv.FormatUint(n, 10)
+}
+
+// latencyOps are the operations of Latency, by their JSON name.
+var latencyOps = []string{"get_hit", "get_miss", "set", "end_to_end"}
+
+// histogram returns the histogram of op, nil if l has none, as once read
+// back from JSON.
+func (l *Latency) histogram(op string) *histogram.Histogram {
+	switch op {
+	case "get_hit":
+		return l.GetHit
+	case "get_miss":
+		return l.GetMiss
+	case "set":
+		return l.Set
+	case "end_to_end":
+		return l.EndToEnd
+	}
+	return nil
+}
+
+// summary returns the summary of the latencies of op, false if none was
+// timed.
+func (l *Latency) summary(op string) (LatencySummary, bool) {
+	if l == nil {
+		return LatencySummary{}, false
+	}
+	if h := l.histogram(op); h != nil {
+		return summarize(h), h.Count() > 0
+	}
+	s, ok := l.summaries[op]
+	return s, ok && s.Count > 0
+}
+
+// endToEnd is the summary of the end-to-end latency of r, false without a
+// backend.
+func (r *BenchmarkResult) endToEnd() (LatencySummary, bool) {
+	return r.Latency.summary("end_to_end")
+}
+
+// LatencySummary is the JSON form of a latency histogram, in nanoseconds.
+type LatencySummary struct {
+	Count uint64  `json:"count"`
+	Mean  float64 `json:"mean_ns"`
+	P50   uint64  `json:"p50_ns"`
+	P90   uint64  `json:"p90_ns"`
+	P99   uint64  `json:"p99_ns"`
+	P999  uint64  `json:"p999_ns"`
+	Max   uint64  `json:"max_ns"`
+}
+
+func summarize(h *histogram.Histogram) LatencySummary {
+	return LatencySummary{
+		Count: h.Count(),
+		Mean:  h.Mean(),
+		P50:   h.Quantile(0.5),
+		P90:   h.Quantile(0.9),
+		P99:   h.Quantile(0.99),
+		P999:  h.Quantile(0.999),
+		Max:   h.Max(),
+	}
+}
+
+func (l *Latency) MarshalJSON() ([]byte, error) {
+	summaries := make(map[string]LatencySummary)
+	for _, op := range latencyOps {
+		if h := l.histogram(op); h != nil {
+			summaries[op] = summarize(h)
+		} else if s, ok := l.summaries[op]; ok {
+			summaries[op] = s
+		}
+	}
+	return json.Marshal(summaries)
+}
+
+// UnmarshalJSON reads the summaries written by MarshalJSON, the histograms
+// themselves are not written.
+func (l *Latency) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &l.summaries)
+}
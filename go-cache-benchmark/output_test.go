@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		backend bool
+		// samples are recorded in every histogram, none leaves them empty.
+		samples []uint64
+	}{
+		{"no samples", false, nil},
+		{"samples", false, []uint64{10, 20, 30, 4000, 50000}},
+		{"backend", true, []uint64{100, 200, 300000}},
+	}
+	for _, tt := range tests {
+		l := newLatency(tt.backend)
+		for _, v := range tt.samples {
+			l.GetHit.Record(v)
+			l.GetMiss.Record(2 * v)
+			l.Set.Record(3 * v)
+			if tt.backend {
+				l.EndToEnd.Record(4 * v)
+			}
+		}
+		b := &Benchmark{Generator: "zipf", Concurrency: 1, Results: []*BenchmarkResult{
+			{CacheName: "sieve", Duration: time.Second, Hits: 1, Misses: 1, Latency: l},
+		}}
+
+		var out bytes.Buffer
+		w, err := newResultWriter(FormatJSON, &out, Metadata{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		var report Report
+		if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		read := report.Benchmarks[0].Results[0].Latency
+
+		for _, op := range latencyOps {
+			want, wantOK := l.summary(op)
+			got, ok := read.summary(op)
+			if got != want || ok != wantOK {
+				t.Errorf("%s: %s read back as %+v, %v, want %+v, %v", tt.name, op, got, ok, want, wantOK)
+			}
+		}
+		// what is read back is written again as it was.
+		first, err := json.Marshal(l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		again, err := json.Marshal(read)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(first, again) {
+			t.Errorf("%s: written again as %s, want %s", tt.name, again, first)
+		}
+		// and printed as it was.
+		var table, readTable strings.Builder
+		b.WriteTable(&table)
+		report.Benchmarks[0].WriteTable(&readTable)
+		if printed := strings.Contains(table.String(), "latency (ns)"); printed != (tt.samples != nil) {
+			t.Errorf("%s: printed the latency: %v", tt.name, printed)
+		}
+		if table.String() != readTable.String() {
+			t.Errorf("%s: printed back as\n%s\nwant\n%s", tt.name, readTable.String(), table.String())
+		}
+	}
+}
+
+// writeResults writes b in format and returns the output.
+func writeResults(t *testing.T, format string, b *Benchmark) string {
+	t.Helper()
+	var out bytes.Buffer
+	w, err := newResultWriter(format, &out, Metadata{Time: time.Unix(0, 0).UTC(), Seed: 7, GoVersion: "go1.22", GOMAXPROCS: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+// outputBenchmarks are the benchmarks the writers are tested with.
+func outputBenchmarks() map[string]*Benchmark {
+	latency := newLatency(true)
+	for _, v := range []uint64{100, 200, 300} {
+		latency.GetHit.Record(v)
+		latency.GetMiss.Record(v)
+		latency.Set.Record(v)
+		latency.EndToEnd.Record(1000 * v)
+	}
+	return map[string]*Benchmark{
+		"entries": {Generator: "zipf", ItemSize: 1000, Workloads: 10000, CacheSizeMultiplier: 0.01, ZipfAlpha: 0.99, Concurrency: 1, Results: []*BenchmarkResult{
+			{CacheName: "sieve", Duration: time.Second, Hits: 75, Misses: 25, Memory: 960, Entries: 10},
+			// -mrc does not measure memory.
+			{CacheName: "optimal", Duration: time.Second, Hits: 80, Misses: 20, Memory: -1, Entries: 10},
+		}},
+		"bytes": {Trace: "trace.csv", ItemSize: 1000, Workloads: 10000, CacheSizeMultiplier: 0.1, ByteCapacity: true, Concurrency: 1, Results: []*BenchmarkResult{
+			{CacheName: "sieve", Duration: time.Second, Hits: 50, Misses: 50, ByteHits: 2048, ByteMisses: 2048, Memory: 500, Entries: 100, Capacity: 10000},
+		}},
+		"backend": {Generator: "uniform", ItemSize: 1000, Workloads: 10000, CacheSizeMultiplier: 0.01, Backend: "100µs", Concurrency: 2, Results: []*BenchmarkResult{
+			{CacheName: "sieve", Duration: 2 * time.Second, Hits: 60, Misses: 40, Memory: 1000, Entries: 10, Latency: latency},
+		}},
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	tests := []struct {
+		benchmark string
+		// columns are the values of the columns of every row, by header.
+		columns []map[string]string
+	}{
+		{"entries", []map[string]string{
+			{"time": "1970-01-01T00:00:00Z", "seed": "7", "gomaxprocs": "4", "workload": "zipf", "alpha": "0.99", "cache": "sieve",
+				"hit_rate": "75", "qps": "100", "bytes_per_entry": "96", "byte_hit_rate": "", "get_hit_p50_ns": "",
+				"backend": "", "end_to_end_mean_ns": "", "byte_capacity": "false", "memory_percent_of_capacity": ""},
+			{"cache": "optimal", "hit_rate": "80", "bytes_per_entry": "", "memory_percent_of_capacity": ""},
+		}},
+		{"bytes", []map[string]string{
+			{"workload": "trace.csv", "cache": "sieve", "hit_rate": "50", "byte_hit_rate": "50", "byte_hits": "2048",
+				"bytes_per_entry": "", "byte_capacity": "true", "memory_percent_of_capacity": "5"},
+		}},
+		{"backend", []map[string]string{
+			{"workload": "uniform", "concurrency": "2", "qps": "50", "backend": "100µs",
+				"get_hit_p999_ns": "300", "set_p999_ns": "300", "end_to_end_mean_ns": "200000", "end_to_end_p99_ns": "300000"},
+		}},
+	}
+	benchmarks := outputBenchmarks()
+	for _, tt := range tests {
+		records, err := csv.NewReader(strings.NewReader(writeResults(t, FormatCSV, benchmarks[tt.benchmark]))).ReadAll()
+		if err != nil {
+			t.Fatalf("%s: %v", tt.benchmark, err)
+		}
+		if len(records) != len(tt.columns)+1 || !slices.Equal(records[0], csvHeader) {
+			t.Fatalf("%s: %d rows with the header %q", tt.benchmark, len(records), records[0])
+		}
+		for i, columns := range tt.columns {
+			row := records[i+1]
+			if len(row) != len(csvHeader) {
+				t.Errorf("%s: row %d has %d columns, want %d", tt.benchmark, i, len(row), len(csvHeader))
+				continue
+			}
+			for name, want := range columns {
+				if got := row[slices.Index(csvHeader, name)]; got != want {
+					t.Errorf("%s: row %d has %s %q, want %q", tt.benchmark, i, name, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestMarkdownWriter(t *testing.T) {
+	tests := []struct {
+		benchmark string
+		lines     []string
+	}{
+		{"entries", []string{
+			"**zipf**, itemSize=1000, workloads=10000, cacheSize=1.00%, zipf's alpha=0.99, concurrency=1",
+			"",
+			"| Cache | Hit rate | QPS | Bytes/Entry |",
+			"|-------|---------:|----:|------------:|",
+			"| sieve | 75.00% | 100 | 96.0 |",
+			"| optimal | 80.00% | 100 | n/a |",
+			"",
+		}},
+		{"bytes", []string{
+			"**trace.csv**, itemSize=1000, workloads=10000, cacheSize=10.00% of bytes, concurrency=1",
+			"",
+			"| Cache | Hit rate | Byte hit rate | Bytes saved | QPS | Memory/Capacity |",
+			"|-------|---------:|--------------:|------------:|----:|----------------:|",
+			"| sieve | 50.00% | 50.00% | 2.0 KiB | 100 | 5.00% |",
+			"",
+		}},
+		{"backend", []string{
+			"**uniform**, itemSize=1000, workloads=10000, cacheSize=1.00%, concurrency=2, backend=100µs",
+			"",
+			"| Cache | Hit rate | QPS | Bytes/Entry | Mean latency | P99 latency |",
+			"|-------|---------:|----:|------------:|-------------:|------------:|",
+			"| sieve | 60.00% | 50 | 100.0 | 200µs | 300µs |",
+			"",
+		}},
+	}
+	benchmarks := outputBenchmarks()
+	for _, tt := range tests {
+		got := writeResults(t, FormatMarkdown, benchmarks[tt.benchmark])
+		if want := strings.Join(tt.lines, "\n") + "\n"; got != want {
+			t.Errorf("%s: wrote\n%s\nwant\n%s", tt.benchmark, got, want)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"io"
+	"sync"
+)
+
+// RequestBatch is a slice of consecutive requests of a trace.
+// Offset is the index of the first request in the trace.
+type RequestBatch struct {
+	Offset   int64
+	Requests []Request
+}
+
+// PrefetchReader reads and decodes the requests of a Reader in a background
+// goroutine, so that decompression and parsing overlap with the replay and
+// the replay stays bound by the cache.
+// It holds at most depth+2 batches of batchSize requests in memory.
+//
+// NextBatch and Release are safe for concurrent use, Read is not.
+type PrefetchReader struct {
+	reader  Reader
+	batches chan *RequestBatch
+	free    chan *RequestBatch
+	done    chan struct{}
+	wg      sync.WaitGroup
+	// err is the error that stopped the producer, it is set before
+	// batches is closed.
+	err error
+
+	cur *RequestBatch
+	pos int
+}
+
+func NewPrefetchReader(r Reader, batchSize, depth int) *PrefetchReader {
+	p := &PrefetchReader{
+		reader:  r,
+		batches: make(chan *RequestBatch, depth),
+		free:    make(chan *RequestBatch, depth+2),
+		done:    make(chan struct{}),
+	}
+	for i := 0; i < depth+2; i++ {
+		p.free <- &RequestBatch{Requests: make([]Request, 0, batchSize)}
+	}
+
+	p.wg.Add(1)
+	go p.produce(batchSize)
+	return p
+}
+
+func (p *PrefetchReader) produce(batchSize int) {
+	defer p.wg.Done()
+	defer close(p.batches)
+
+	var offset int64
+	for {
+		var b *RequestBatch
+		select {
+		case b = <-p.free:
+		case <-p.done:
+			return
+		}
+
+		b.Offset = offset
+		b.Requests = b.Requests[:batchSize]
+		n := 0
+		var err error
+		for ; n < batchSize; n++ {
+			if err = p.reader.Read(&b.Requests[n]); err != nil {
+				break
+			}
+		}
+		b.Requests = b.Requests[:n]
+		offset += int64(n)
+
+		if n > 0 {
+			select {
+			case p.batches <- b:
+			case <-p.done:
+				return
+			}
+		}
+		if err != nil {
+			p.err = err
+			return
+		}
+	}
+}
+
+// NextBatch returns the next batch of requests, the caller must Release it
+// once replayed. It returns io.EOF after the last batch.
+func (p *PrefetchReader) NextBatch() (*RequestBatch, error) {
+	b, ok := <-p.batches
+	if !ok {
+		return nil, p.err
+	}
+	return b, nil
+}
+
+// Release gives a batch back to the producer.
+func (p *PrefetchReader) Release(b *RequestBatch) {
+	p.free <- b
+}
+
+// Read reads the next request, it returns io.EOF after the last one.
+func (p *PrefetchReader) Read(r *Request) error {
+	for p.cur == nil || p.pos == len(p.cur.Requests) {
+		if p.cur != nil {
+			p.Release(p.cur)
+			p.cur = nil
+		}
+		b, err := p.NextBatch()
+		if err != nil {
+			return err
+		}
+		p.cur, p.pos = b, 0
+	}
+	*r = p.cur.Requests[p.pos]
+	p.pos++
+	return nil
+}
+
+// Close stops the producer and closes the underlying reader.
+func (p *PrefetchReader) Close() error {
+	close(p.done)
+	p.wg.Wait()
+	return p.reader.Close()
+}
+
+// limitedReader stops a Reader after n requests.
+type limitedReader struct {
+	Reader
+	n int
+}
+
+func (l *limitedReader) Read(r *Request) error {
+	if l.n <= 0 {
+		return io.EOF
+	}
+	l.n--
+	return l.Reader.Read(r)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+)
+
+// sliceReader reads the keys 0 to n-1, then err, io.EOF if nil.
+type sliceReader struct {
+	n, next int
+	err     error
+	closed  bool
+}
+
+func (s *sliceReader) Read(r *Request) error {
+	if s.next == s.n {
+		if s.err != nil {
+			return s.err
+		}
+		return io.EOF
+	}
+	*r = Request{Key: fmt.Sprint(s.next), NextAccess: -1}
+	s.next++
+	return nil
+}
+
+func (s *sliceReader) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestPrefetchReader(t *testing.T) {
+	errBroken := errors.New("broken trace")
+	tests := []struct {
+		name       string
+		requests   int
+		batchSize  int
+		limit      int
+		err        error
+		wantKeys   int
+		wantBounds []int64
+	}{
+		{"empty", 0, 4, 0, nil, 0, nil},
+		{"one partial batch", 3, 4, 0, nil, 3, []int64{0}},
+		{"full batches", 8, 4, 0, nil, 8, []int64{0, 4}},
+		{"last batch partial", 10, 4, 0, nil, 10, []int64{0, 4, 8}},
+		{"limit", 10, 4, 6, nil, 6, []int64{0, 4}},
+		// the requests read before an error are still replayed.
+		{"error", 6, 4, 0, errBroken, 6, []int64{0, 4}},
+	}
+	for _, tt := range tests {
+		src := &sliceReader{n: tt.requests, err: tt.err}
+		var r Reader = src
+		if tt.limit > 0 {
+			r = &limitedReader{Reader: r, n: tt.limit}
+		}
+		p := NewPrefetchReader(r, tt.batchSize, 2)
+
+		var offsets []int64
+		keys := 0
+		var err error
+		for {
+			var b *RequestBatch
+			if b, err = p.NextBatch(); err != nil {
+				break
+			}
+			offsets = append(offsets, b.Offset)
+			for i, req := range b.Requests {
+				if want := fmt.Sprint(b.Offset + int64(i)); req.Key != want {
+					t.Errorf("%s: request %d of the batch at %d is %s, want %s", tt.name, i, b.Offset, req.Key, want)
+				}
+			}
+			keys += len(b.Requests)
+			p.Release(b)
+		}
+		want := tt.err
+		if want == nil {
+			want = io.EOF
+		}
+		if !errors.Is(err, want) {
+			t.Errorf("%s: NextBatch returned %v, want %v", tt.name, err, want)
+		}
+		// the error stays, every worker stops on it.
+		if _, again := p.NextBatch(); !errors.Is(again, want) {
+			t.Errorf("%s: NextBatch returned %v after the last batch, want %v", tt.name, again, want)
+		}
+		if keys != tt.wantKeys || fmt.Sprint(offsets) != fmt.Sprint(tt.wantBounds) {
+			t.Errorf("%s: %d requests in batches at %v, want %d at %v", tt.name, keys, offsets, tt.wantKeys, tt.wantBounds)
+		}
+		if err := p.Close(); err != nil || !src.closed {
+			t.Errorf("%s: Close returned %v, closed the trace: %v", tt.name, err, src.closed)
+		}
+	}
+}
+
+func TestPrefetchReaderRead(t *testing.T) {
+	p := NewPrefetchReader(&sliceReader{n: 10}, 3, 1)
+	defer p.Close()
+	got, err := readAll(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 10 {
+		t.Fatalf("read %d requests, want 10", len(got))
+	}
+	for i, req := range got {
+		if req.Key != fmt.Sprint(i) {
+			t.Errorf("request %d is %s", i, req.Key)
+		}
+	}
+}
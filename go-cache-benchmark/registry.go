@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hey-kong/shift/go-cache-benchmark/cache"
+)
+
+type NewCacheFunc func(size int) cache.Cache
+
+// NewSizedCacheFunc returns a cache of capacity bytes.
+type NewSizedCacheFunc func(capacity int64) cache.Cache
+
+type registeredCache struct {
+	name     string
+	new      NewCacheFunc
+	selected bool // run when no cache is selected explicitly
+}
+
+// registry lists every cache the benchmark can run, by name.
+var registry = []registeredCache{
+	{"sieve", cache.NewSieve, true},
+	{"shift", cache.NewShift, true},
+	{"shift-3q", cache.NewShift3Q, true},
+	{"shift-4q", cache.NewShift4Q, true},
+	{"shift-clock", cache.NewShiftClock, true},
+	{"s3-fifo", cache.NewS3FIFO, true},
+	{"lru-hashicorp", cache.NewLRU, true},
+	{"two-queue", cache.NewTwoQueue, true},
+	{"lru-groupcache", cache.NewLRUGroupCache, true},
+	{"tinylfu", cache.NewTinyLFU, true},
+	{"slru", cache.NewSLRU, true},
+	{"s4lru", cache.NewS4LRU, true},
+	{"clock", cache.NewClock, true},
+	{"freelru-synced", cache.NewFreeLRUSynced, true},
+	{"freelru-sharded", cache.NewFreeLRUSharded, true},
+	{"optimal", cache.NewBelady, true},
+	{"otter", cache.NewOtter, false},
+	{"lfu", cache.NewLFU, false},
+}
+
+// sizedRegistry lists the caches that can be given a capacity in bytes.
+var sizedRegistry = map[string]NewSizedCacheFunc{
+	"optimal": cache.NewSizedBelady,
+	"otter":   cache.NewSizedOtter,
+}
+
+// lookupCaches returns the constructors of the named caches.
+// No names selects the default caches and "all" selects every cache.
+func lookupCaches(names []string) ([]NewCacheFunc, error) {
+	selected, err := lookupRegistered(names)
+	if err != nil {
+		return nil, err
+	}
+	caches := make([]NewCacheFunc, len(selected))
+	for i, c := range selected {
+		caches[i] = c.new
+	}
+	return caches, nil
+}
+
+// lookupByteCaches is lookupCaches for capacities in bytes. The constructors
+// still take a number of entries, of meanSize bytes each. Caches without a
+// capacity in bytes keep that number of entries.
+func lookupByteCaches(names []string, meanSize float64) ([]NewCacheFunc, error) {
+	selected, err := lookupRegistered(names)
+	if err != nil {
+		return nil, err
+	}
+	caches := make([]NewCacheFunc, len(selected))
+	for i, c := range selected {
+		caches[i] = c.new
+		if sized, ok := sizedRegistry[c.name]; ok {
+			caches[i] = func(size int) cache.Cache {
+				return sized(int64(float64(size) * meanSize))
+			}
+		}
+	}
+	return caches, nil
+}
+
+func lookupRegistered(names []string) ([]registeredCache, error) {
+	var caches []registeredCache
+	if len(names) == 0 {
+		for _, c := range registry {
+			if c.selected {
+				caches = append(caches, c)
+			}
+		}
+		return caches, nil
+	}
+
+	for _, name := range names {
+		if name == "all" {
+			return registry, nil
+		}
+	}
+
+	for _, name := range names {
+		found := false
+		for _, c := range registry {
+			if c.name == name {
+				caches = append(caches, c)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown cache %q, available caches: %s", name, strings.Join(cacheNames(), ", "))
+		}
+	}
+	return caches, nil
+}
+
+func cacheNames() []string {
+	names := make([]string, 0, len(registry))
+	for _, c := range registry {
+		names = append(names, c.name)
+	}
+	return names
+}
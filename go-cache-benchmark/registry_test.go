@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/hey-kong/shift/go-cache-benchmark/cache"
+)
+
+func TestRegistry(t *testing.T) {
+	// a size that no cache rounds up.
+	const size = 16
+	// a working set that fits in the smallest segment of every cache.
+	keys := make([]string, 8*size)
+	for i := range keys {
+		keys[i] = fmt.Sprint(i % (size / 8))
+	}
+	next := cache.NextAccess(keys)
+
+	for _, r := range registry {
+		// the sizes that round to no segment in some caches.
+		for n := 1; n <= 4; n++ {
+			c := r.new(n)
+			for i, key := range keys {
+				if o, ok := c.(cache.Oracle); ok {
+					o.SetNextAccess(next[i])
+				}
+				if !c.Get(key) {
+					c.Set(key)
+				}
+			}
+			c.Close()
+		}
+
+		c := r.new(size)
+		if c.Name() != r.name {
+			t.Errorf("%s: the cache is named %s", r.name, c.Name())
+		}
+		o, oracle := c.(cache.Oracle)
+		hits := 0
+		for i, key := range keys {
+			if oracle {
+				o.SetNextAccess(next[i])
+			}
+			if c.Get(key) {
+				hits++
+			} else {
+				c.Set(key)
+			}
+		}
+		c.Close()
+		if hits == 0 {
+			t.Errorf("%s: no hit on %d keys in %d entries", r.name, size/8, size)
+		}
+	}
+}
+
+func TestLookupByteCaches(t *testing.T) {
+	tests := []struct {
+		names []string
+		// sized are the caches built with a capacity in bytes.
+		sized []bool
+		err   bool
+	}{
+		{names: []string{"sieve"}, sized: []bool{false}},
+		{names: []string{"optimal", "otter", "shift"}, sized: []bool{true, true, false}},
+		{names: []string{"fifo"}, err: true},
+	}
+	for _, tt := range tests {
+		caches, err := lookupByteCaches(tt.names, 100)
+		if (err != nil) != tt.err {
+			t.Errorf("%v: error %v", tt.names, err)
+			continue
+		}
+		if len(caches) != len(tt.sized) {
+			t.Errorf("%v: %d caches, want %d", tt.names, len(caches), len(tt.sized))
+			continue
+		}
+		for i, newCache := range caches {
+			c := newCache(10)
+			if _, sized := c.(cache.Sized); sized != tt.sized[i] {
+				t.Errorf("%s: sized %v, want %v", tt.names[i], sized, tt.sized[i])
+			}
+			c.Close()
+		}
+	}
+}
+
+func TestSetSize(t *testing.T) {
+	// the objects are set in order, each accessed again at its next; the
+	// capacity is 10 entries of 100 bytes.
+	type object struct {
+		key  string
+		size int64
+		next int64
+	}
+	tests := []struct {
+		name    string
+		objects []object
+		cached  []string
+	}{
+		{
+			name:    "fits",
+			objects: []object{{"a", 400, 10}, {"b", 600, 11}},
+			cached:  []string{"a", "b"},
+		},
+		{
+			name:    "larger than the cache",
+			objects: []object{{"a", 1001, 10}},
+		},
+		{
+			// the object reused furthest away goes first.
+			name:    "evicts until it fits",
+			objects: []object{{"a", 400, 20}, {"b", 400, 10}, {"c", 300, 12}},
+			cached:  []string{"b", "c"},
+		},
+		{
+			// the new object is the one reused furthest away.
+			name:    "bypasses",
+			objects: []object{{"a", 600, 10}, {"b", 600, 20}},
+			cached:  []string{"a"},
+		},
+		{
+			name:    "grows",
+			objects: []object{{"a", 400, 20}, {"b", 400, 10}, {"b", 700, 10}},
+			cached:  []string{"b"},
+		},
+		{
+			// a size of 0 is unknown, and counts as 1 byte.
+			name:    "unknown size",
+			objects: []object{{"a", 0, 10}, {"b", 999, 11}},
+			cached:  []string{"a", "b"},
+		},
+	}
+	caches, err := lookupByteCaches([]string{"optimal"}, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		c := caches[0](10)
+		s := c.(cache.Sized)
+		o := c.(cache.Oracle)
+		for _, obj := range tt.objects {
+			o.SetNextAccess(obj.next)
+			s.SetSize(obj.key, obj.size)
+		}
+		var cached []string
+		for _, key := range []string{"a", "b", "c"} {
+			if c.Get(key) {
+				cached = append(cached, key)
+			}
+		}
+		if !slices.Equal(cached, tt.cached) {
+			t.Errorf("%s: cached %v, want %v", tt.name, cached, tt.cached)
+		}
+	}
+}
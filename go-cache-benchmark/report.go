@@ -0,0 +1,284 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/hey-kong/shift/go-cache-benchmark/chart"
+)
+
+// ReportConfig holds the parameters of the report command.
+type ReportConfig struct {
+	Results []string
+	Dir     string
+}
+
+func parseReportConfig(args []string, output io.Writer) (*ReportConfig, error) {
+	c := &ReportConfig{Dir: "."}
+
+	fs := flag.NewFlagSet("go-cache-benchmark report", flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&c.Dir, "dir", c.Dir, "`directory` to write the charts to")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: go-cache-benchmark report [flags] results.json...\n\n")
+		fmt.Fprintf(fs.Output(), "Renders SVG charts of JSON results, written with -format json.\n\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return nil, errors.New("report needs results")
+	}
+	c.Results = fs.Args()
+	return c, nil
+}
+
+// figure collects the points of a chart, averaged over repeated runs.
+type figure struct {
+	name  string
+	title string
+	// unit labels the values of a bar chart.
+	unit   string
+	caches []string
+	// sums and counts of the values of every cache at every x.
+	xs     map[string][]float64
+	sums   map[string]map[float64]float64
+	counts map[string]map[float64]int
+}
+
+func (f *figure) add(cache string, x, y float64) {
+	if f.sums == nil {
+		f.xs = make(map[string][]float64)
+		f.sums = make(map[string]map[float64]float64)
+		f.counts = make(map[string]map[float64]int)
+	}
+	if _, ok := f.sums[cache]; !ok {
+		f.caches = append(f.caches, cache)
+		f.sums[cache] = make(map[float64]float64)
+		f.counts[cache] = make(map[float64]int)
+	}
+	if _, ok := f.sums[cache][x]; !ok {
+		f.xs[cache] = append(f.xs[cache], x)
+	}
+	f.sums[cache][x] += y
+	f.counts[cache][x]++
+}
+
+// points returns the averaged points of cache, by x.
+func (f *figure) points(cache string) []chart.Point {
+	xs := append([]float64(nil), f.xs[cache]...)
+	slices.Sort(xs)
+	points := make([]chart.Point, len(xs))
+	for i, x := range xs {
+		points[i] = chart.Point{X: x, Y: f.sums[cache][x] / float64(f.counts[cache][x])}
+	}
+	return points
+}
+
+// distinctX returns the number of distinct x of every cache together.
+func (f *figure) distinctX() int {
+	seen := make(map[float64]struct{})
+	for _, xs := range f.xs {
+		for _, x := range xs {
+			seen[x] = struct{}{}
+		}
+	}
+	return len(seen)
+}
+
+// figures keeps figures by name in the order they are created.
+type figures struct {
+	byName map[string]*figure
+	order  []*figure
+}
+
+func (fs *figures) get(name, title string) *figure {
+	if fs.byName == nil {
+		fs.byName = make(map[string]*figure)
+	}
+	f, ok := fs.byName[name]
+	if !ok {
+		f = &figure{name: name, title: title}
+		fs.byName[name] = f
+		fs.order = append(fs.order, f)
+	}
+	return f
+}
+
+var unsafeFileName = regexp.MustCompile(`[^A-Za-z0-9.=_-]+`)
+
+// fileName turns the parameters of a figure into a file name.
+func fileName(parts ...string) string {
+	return unsafeFileName.ReplaceAllString(strings.Join(parts, "-"), "_") + ".svg"
+}
+
+// settings returns the parameters of b, other than the workload, the cache
+// size and the concurrency, that runs must share to be in the same figure.
+func settings(b *Benchmark) []string {
+	var s []string
+	if b.GeneratorOptions != "" {
+		s = append(s, b.GeneratorOptions)
+	}
+	if b.ByteCapacity {
+		s = append(s, "capacity in bytes")
+	}
+	if b.Backend != "" {
+		s = append(s, "backend="+b.Backend)
+	}
+	if b.Warmup > 0 {
+		s = append(s, fmt.Sprintf("warmup=%d", b.Warmup))
+	}
+	return s
+}
+
+// describe returns the parameters of b that are common to every point of a figure.
+func describe(b *Benchmark, ratio, concurrency bool) string {
+	s := fmt.Sprintf("%s, items=%d", b.workloadName(), b.ItemSize)
+	if b.ZipfAlpha > 0 {
+		s += fmt.Sprintf(", alpha=%g", b.ZipfAlpha)
+	}
+	for _, setting := range settings(b) {
+		s += ", " + setting
+	}
+	if ratio {
+		s += fmt.Sprintf(", cache=%g%%", b.CacheSizeMultiplier*100)
+	}
+	if concurrency {
+		s += fmt.Sprintf(", concurrency=%d", b.Concurrency)
+	}
+	return s
+}
+
+// report renders the charts of the results of c and returns their paths.
+func report(c *ReportConfig) ([]string, error) {
+	var missRatio, qps, memory figures
+	for _, path := range c.Results {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var r Report
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("could not read results %s: %w", path, err)
+		}
+
+		for _, b := range r.Benchmarks {
+			workload := strings.Join(append([]string{fmt.Sprintf("%s-items%d-alpha%g", b.workloadName(), b.ItemSize, b.ZipfAlpha)},
+				settings(b)...), "-")
+			mr := missRatio.get(
+				fileName("miss-ratio", workload, fmt.Sprintf("c%d", b.Concurrency)),
+				"Miss ratio, "+describe(b, false, true))
+			q := qps.get(
+				fileName("qps", workload, fmt.Sprintf("ratio%g", b.CacheSizeMultiplier)),
+				"Throughput, "+describe(b, true, false))
+			// the memory of caches sized in bytes is relative to their
+			// capacity in bytes.
+			var m *figure
+			if b.ByteCapacity {
+				m = memory.get(
+					fileName("memory", workload, fmt.Sprintf("ratio%g", b.CacheSizeMultiplier)),
+					"Memory per byte of capacity, "+describe(b, true, false))
+				m.unit = "% of the capacity in bytes"
+			} else {
+				m = memory.get(
+					fileName("memory", workload, fmt.Sprintf("ratio%g", b.CacheSizeMultiplier)),
+					"Memory per entry, "+describe(b, true, false))
+				m.unit = "bytes per entry"
+			}
+			for _, result := range b.Results {
+				mr.add(result.CacheName, b.CacheSizeMultiplier*100, 100-result.hitRate())
+				q.add(result.CacheName, float64(b.Concurrency), result.qps(result.operations()))
+				// simulations of -mrc do not measure memory.
+				if result.hasMemory() {
+					m.add(result.CacheName, 0, result.memoryPerCapacity())
+				}
+			}
+		}
+	}
+
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	var paths []string
+	write := func(name string, svg interface{ WriteSVG(io.Writer) error }) error {
+		path := filepath.Join(c.Dir, name)
+		f, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		if err := errors.Join(svg.WriteSVG(f), f.Close()); err != nil {
+			return err
+		}
+		paths = append(paths, path)
+		return nil
+	}
+
+	lines := []struct {
+		figures *figures
+		x, y    chart.Axis
+	}{
+		{&missRatio, chart.Axis{Label: "cache size (% of keys)", Log: true, Format: chart.FormatPercent}, chart.Axis{Label: "miss ratio", Format: chart.FormatPercent}},
+		{&qps, chart.Axis{Label: "concurrency", Log: true}, chart.Axis{Label: "QPS"}},
+	}
+	for _, l := range lines {
+		for _, f := range l.figures.order {
+			// a single point per cache is not a curve.
+			if f.distinctX() < 2 {
+				continue
+			}
+			line := &chart.Line{Title: f.title, X: l.x, Y: l.y}
+			for _, cache := range f.caches {
+				line.Series = append(line.Series, chart.Series{Name: cache, Points: f.points(cache)})
+			}
+			if err := write(f.name, line); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for _, f := range memory.order {
+		if len(f.caches) == 0 {
+			continue
+		}
+		bar := &chart.Bar{Title: f.title, Y: chart.Axis{Label: f.unit}}
+		for _, cache := range f.caches {
+			bar.Labels = append(bar.Labels, cache)
+			bar.Values = append(bar.Values, f.points(cache)[0].Y)
+		}
+		if err := write(f.name, bar); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// reportMain runs the report command and returns its exit code.
+func reportMain(args []string) int {
+	c, err := parseReportConfig(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go-cache-benchmark: %v\n", err)
+		return 2
+	}
+	paths, err := report(c)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "go-cache-benchmark: %v\n", err)
+		return 1
+	}
+	for _, path := range paths {
+		fmt.Println(path)
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReport(t *testing.T) {
+	// benchmark returns a run of sieve and shift at ratio and concurrency,
+	// with settings changed by set.
+	benchmark := func(ratio float64, concurrency int, set func(b *Benchmark)) *Benchmark {
+		b := &Benchmark{Generator: "zipf", ItemSize: 1000, Workloads: 10000, CacheSizeMultiplier: ratio, ZipfAlpha: 0.99, Concurrency: concurrency}
+		for _, cache := range []string{"sieve", "shift"} {
+			b.Results = append(b.Results, &BenchmarkResult{
+				CacheName: cache, Duration: time.Second, Hits: 60, Misses: 40, Memory: 1000, Entries: 10,
+			})
+		}
+		if set != nil {
+			set(b)
+		}
+		return b
+	}
+
+	tests := []struct {
+		name       string
+		benchmarks []*Benchmark
+		files      []string
+		// titles are the titles of the files, in order.
+		titles []string
+	}{
+		{
+			name:       "single run",
+			benchmarks: []*Benchmark{benchmark(0.01, 1, nil)},
+			// a single cache size and concurrency draw no curve.
+			files:  []string{"memory-zipf-items1000-alpha0.99-ratio0.01.svg"},
+			titles: []string{"Memory per entry, zipf, items=1000, alpha=0.99, cache=1%"},
+		},
+		{
+			name:       "curves",
+			benchmarks: []*Benchmark{benchmark(0.01, 1, nil), benchmark(0.1, 1, nil), benchmark(0.01, 4, nil)},
+			files: []string{
+				"miss-ratio-zipf-items1000-alpha0.99-c1.svg",
+				"qps-zipf-items1000-alpha0.99-ratio0.01.svg",
+				"memory-zipf-items1000-alpha0.99-ratio0.01.svg",
+				"memory-zipf-items1000-alpha0.99-ratio0.1.svg",
+			},
+			titles: []string{
+				"Miss ratio, zipf, items=1000, alpha=0.99, concurrency=1",
+				"Throughput, zipf, items=1000, alpha=0.99, cache=1%",
+				"Memory per entry, zipf, items=1000, alpha=0.99, cache=1%",
+				"Memory per entry, zipf, items=1000, alpha=0.99, cache=10%",
+			},
+		},
+		{
+			// runs with other settings are not points of the same curve.
+			name: "settings",
+			benchmarks: []*Benchmark{
+				benchmark(0.01, 1, nil),
+				benchmark(0.1, 1, func(b *Benchmark) { b.Warmup = 1000 }),
+				benchmark(0.01, 1, func(b *Benchmark) { b.Backend = "100µs" }),
+				benchmark(0.01, 1, func(b *Benchmark) { b.ByteCapacity = true; b.Results[0].Capacity = 100000 }),
+			},
+			files: []string{
+				"memory-zipf-items1000-alpha0.99-ratio0.01.svg",
+				"memory-zipf-items1000-alpha0.99-warmup=1000-ratio0.1.svg",
+				"memory-zipf-items1000-alpha0.99-backend=100_s-ratio0.01.svg",
+				"memory-zipf-items1000-alpha0.99-capacity_in_bytes-ratio0.01.svg",
+			},
+			titles: []string{
+				"Memory per entry, zipf, items=1000, alpha=0.99, cache=1%",
+				"Memory per entry, zipf, items=1000, alpha=0.99, warmup=1000, cache=10%",
+				"Memory per entry, zipf, items=1000, alpha=0.99, backend=100µs, cache=1%",
+				"Memory per byte of capacity, zipf, items=1000, alpha=0.99, capacity in bytes, cache=1%",
+			},
+		},
+		{
+			// repeated runs are averaged into the same points.
+			name:       "repeated runs",
+			benchmarks: []*Benchmark{benchmark(0.01, 1, nil), benchmark(0.01, 1, nil)},
+			files:      []string{"memory-zipf-items1000-alpha0.99-ratio0.01.svg"},
+			titles:     []string{"Memory per entry, zipf, items=1000, alpha=0.99, cache=1%"},
+		},
+	}
+	for _, tt := range tests {
+		dir := t.TempDir()
+		data, err := json.Marshal(Report{Benchmarks: tt.benchmarks})
+		if err != nil {
+			t.Fatal(err)
+		}
+		results := filepath.Join(dir, "results.json")
+		if err := os.WriteFile(results, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		charts := filepath.Join(dir, "charts")
+		paths, err := report(&ReportConfig{Results: []string{results}, Dir: charts})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		var files []string
+		for _, path := range paths {
+			files = append(files, filepath.Base(path))
+		}
+		if !slices.Equal(files, tt.files) {
+			t.Errorf("%s: files %q, want %q", tt.name, files, tt.files)
+			continue
+		}
+		for i, path := range paths {
+			svg, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(svg), ">"+tt.titles[i]+"</text>") {
+				t.Errorf("%s: %s has not the title %q", tt.name, files[i], tt.titles[i])
+			}
+			for _, cache := range []string{"sieve", "shift"} {
+				if !strings.Contains(string(svg), cache) {
+					t.Errorf("%s: %s has no %s", tt.name, files[i], cache)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	TraceFormatOracleGeneral = "oracleGeneral"
+	TraceFormatCSV           = "csv"
+	TraceFormatText          = "txt"
+)
+
+// TraceOptions tells how to read a trace.
+type TraceOptions struct {
+	// Format is one of the TraceFormat constants, empty to detect it.
+	Format string
+	CSV    CSVOptions
+}
+
+func defaultTraceOptions() TraceOptions {
+	return TraceOptions{CSV: defaultCSVOptions()}
+}
+
+// oracleGeneralRecordSize is the size of a record of libCacheSim's
+// oracleGeneral binary format, little endian and packed:
+//
+//	struct {
+//	  uint32_t real_time;
+//	  uint64_t obj_id;
+//	  uint32_t obj_size;
+//	  int64_t  next_access_vtime;
+//	};
+const oracleGeneralRecordSize = 24
+
+// OracleGeneralReader streams a libCacheSim oracleGeneral binary trace.
+type OracleGeneralReader struct {
+	f   io.Closer
+	r   *bufio.Reader
+	buf [oracleGeneralRecordSize]byte
+}
+
+func NewOracleGeneralReader(r io.ReadCloser) *OracleGeneralReader {
+	return &OracleGeneralReader{
+		f: r,
+		r: bufio.NewReaderSize(r, 1<<20),
+	}
+}
+
+func (o *OracleGeneralReader) Read(r *Request) error {
+	if _, err := io.ReadFull(o.r, o.buf[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("truncated oracleGeneral record: %w", err)
+		}
+		return err
+	}
+
+	r.Timestamp = uint64(binary.LittleEndian.Uint32(o.buf[0:4]))
+	r.Key = strconv.FormatUint(binary.LittleEndian.Uint64(o.buf[4:12]), 10)
+	r.Size = binary.LittleEndian.Uint32(o.buf[12:16])
+	r.Op = ""
+	// next_access_vtime counts requests from 1, it is -1 or INT64_MAX if there is none.
+	next := int64(binary.LittleEndian.Uint64(o.buf[16:24]))
+	if next <= 0 || next == math.MaxInt64 {
+		r.NextAccess = -1
+	} else {
+		r.NextAccess = next - 1
+	}
+	return nil
+}
+
+func (o *OracleGeneralReader) Close() error {
+	return o.f.Close()
+}
+
+// compression returns the compression suffix of path, if any.
+func compression(path string) string {
+	for _, ext := range []string{".gz", ".zst", ".zstd"} {
+		if strings.HasSuffix(strings.ToLower(path), ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// detectTraceFormat guesses the format of a trace from its file name,
+// ignoring a compression suffix.
+func detectTraceFormat(path string) (string, error) {
+	name := strings.ToLower(filepath.Base(path))
+	name = strings.TrimSuffix(name, compression(name))
+	switch {
+	case strings.Contains(name, ".oraclegeneral"):
+		return TraceFormatOracleGeneral, nil
+	case strings.HasSuffix(name, ".csv"):
+		return TraceFormatCSV, nil
+	case strings.HasSuffix(name, ".txt"):
+		return TraceFormatText, nil
+	}
+	return "", fmt.Errorf("cannot detect the format of %s, set -trace-format", path)
+}
+
+// openTrace opens the trace at path, .gz and .zst files are decompressed
+// transparently, as a stream.
+func openTrace(path string, options TraceOptions) (Reader, error) {
+	format := options.Format
+	if format == "" {
+		var err error
+		if format, err = detectTraceFormat(path); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var rc io.ReadCloser = f
+	switch compression(path) {
+	case ".gz":
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not read %s: %w", path, err)
+		}
+		rc = &stackedReadCloser{Reader: zr, closers: []io.Closer{zr, f}}
+	case ".zst", ".zstd":
+		// a single decoding goroutine keeps the memory bounded by the
+		// window size of the frame, prefetching is done by PrefetchReader.
+		zr, err := zstd.NewReader(f, zstd.WithDecoderConcurrency(1), zstd.WithDecoderLowmem(true))
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not read %s: %w", path, err)
+		}
+		rc = &stackedReadCloser{Reader: zr, closers: []io.Closer{zstdCloser{zr}, f}}
+	}
+
+	switch format {
+	case TraceFormatOracleGeneral:
+		return NewOracleGeneralReader(rc), nil
+	case TraceFormatCSV:
+		return NewCSVReader(rc, options.CSV), nil
+	case TraceFormatText:
+		return NewTextReader(rc), nil
+	}
+	rc.Close()
+	return nil, fmt.Errorf("unknown trace format %q", format)
+}
+
+// stackedReadCloser reads from a decompressor and closes it with the
+// underlying file.
+type stackedReadCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (s *stackedReadCloser) Close() error {
+	var errs []error
+	for _, c := range s.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// zstdCloser adapts zstd.Decoder, whose Close returns nothing.
+type zstdCloser struct {
+	d *zstd.Decoder
+}
+
+func (z zstdCloser) Close() error {
+	z.d.Close()
+	return nil
+}
+
+// TraceReader reads the requests of a trace file once, see loadTrace.
+type TraceReader struct {
+	path   string
+	reader Reader
+}
+
+func NewTraceReader(path string, options TraceOptions) (*TraceReader, error) {
+	reader, err := openTrace(path, options)
+	if err != nil {
+		return nil, err
+	}
+	return &TraceReader{path: path, reader: reader}, nil
+}
+
+func (t *TraceReader) Name() string {
+	return filepath.Base(t.path)
+}
+
+// HasNextAccess reports whether the trace carries the next access of each request.
+func (t *TraceReader) HasNextAccess() bool {
+	_, ok := t.reader.(*OracleGeneralReader)
+	return ok
+}
+
+// Read reads the next request of the trace, it returns io.EOF after the last one.
+func (t *TraceReader) Read(r *Request) error {
+	return t.reader.Read(r)
+}
+
+func (t *TraceReader) Close() error {
+	return t.reader.Close()
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// oracleGeneralRecord is a record of an oracleGeneral trace.
+type oracleGeneralRecord struct {
+	time uint32
+	id   uint64
+	size uint32
+	next int64
+}
+
+// oracleGeneral encodes records in the oracleGeneral binary format.
+func oracleGeneral(records ...oracleGeneralRecord) []byte {
+	var buf bytes.Buffer
+	for _, r := range records {
+		binary.Write(&buf, binary.LittleEndian, r.time)
+		binary.Write(&buf, binary.LittleEndian, r.id)
+		binary.Write(&buf, binary.LittleEndian, r.size)
+		binary.Write(&buf, binary.LittleEndian, r.next)
+	}
+	return buf.Bytes()
+}
+
+func TestOracleGeneralReader(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+		want  []Request
+		// err is a substring of the error, empty for none.
+		err string
+	}{
+		{name: "empty"},
+		{
+			name: "records",
+			input: oracleGeneral(
+				oracleGeneralRecord{time: 1, id: 42, size: 100, next: 3},
+				oracleGeneralRecord{time: 2, id: math.MaxUint64, size: 0, next: -1},
+				oracleGeneralRecord{time: 3, id: 42, size: 100, next: math.MaxInt64},
+			),
+			// next accesses count from 1 in the trace, from 0 in Request.
+			want: []Request{
+				{Timestamp: 1, Key: "42", Size: 100, NextAccess: 2},
+				{Timestamp: 2, Key: "18446744073709551615", NextAccess: -1},
+				{Timestamp: 3, Key: "42", Size: 100, NextAccess: -1},
+			},
+		},
+		{
+			name:  "no next access",
+			input: oracleGeneral(oracleGeneralRecord{time: 1, id: 7, size: 1, next: 0}),
+			want:  []Request{{Timestamp: 1, Key: "7", Size: 1, NextAccess: -1}},
+		},
+		{
+			name:  "truncated",
+			input: oracleGeneral(oracleGeneralRecord{id: 1, next: 2}, oracleGeneralRecord{id: 2})[:oracleGeneralRecordSize+10],
+			want:  []Request{{Key: "1", NextAccess: 1}},
+			err:   "truncated oracleGeneral record",
+		},
+	}
+	for _, tt := range tests {
+		r := NewOracleGeneralReader(io.NopCloser(bytes.NewReader(tt.input)))
+		got, err := readAll(r)
+		if (tt.err == "" && err != nil) || (tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err))) {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: read %+v, want %+v", tt.name, got, tt.want)
+		}
+		if err := r.Close(); err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+func TestDetectTraceFormat(t *testing.T) {
+	tests := []struct {
+		path, format string
+	}{
+		{"data/cloudPhysicsIO.oracleGeneral.bin", TraceFormatOracleGeneral},
+		{"w44.oracleGeneral.zst", TraceFormatOracleGeneral},
+		{"trace.CSV", TraceFormatCSV},
+		{"trace.csv.gz", TraceFormatCSV},
+		{"keys.txt.zstd", TraceFormatText},
+		{"trace.bin", ""},
+		{"trace.gz", ""},
+	}
+	for _, tt := range tests {
+		format, err := detectTraceFormat(tt.path)
+		if format != tt.format || (err != nil) != (tt.format == "") {
+			t.Errorf("detectTraceFormat(%q) = %q, %v, want %q", tt.path, format, err, tt.format)
+		}
+	}
+}
+
+func TestOpenTrace(t *testing.T) {
+	records := oracleGeneral(
+		oracleGeneralRecord{time: 1, id: 1, size: 10, next: 2},
+		oracleGeneralRecord{time: 2, id: 1, size: 10, next: -1},
+	)
+	want := []Request{{Timestamp: 1, Key: "1", Size: 10, NextAccess: 1}, {Timestamp: 2, Key: "1", Size: 10, NextAccess: -1}}
+
+	gz := func(data []byte) []byte {
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+		return buf.Bytes()
+	}
+	zst := func(data []byte) []byte {
+		w, err := zstd.NewWriter(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer w.Close()
+		return w.EncodeAll(data, nil)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		options TraceOptions
+		want    []Request
+		err     string
+	}{
+		{name: "trace.oracleGeneral.bin", data: records, want: want},
+		{name: "trace.oracleGeneral.bin.gz", data: gz(records), want: want},
+		{name: "trace.oracleGeneral.bin.zst", data: zst(records), want: want},
+		// the format given wins over the file name.
+		{name: "trace.dat", data: records, options: TraceOptions{Format: TraceFormatOracleGeneral}, want: want},
+		{name: "keys.txt.gz", data: gz([]byte("a\nb\n")), want: []Request{{Key: "a", NextAccess: -1}, {Key: "b", NextAccess: -1}}},
+		{name: "trace.dat", data: records, err: "cannot detect the format"},
+		{name: "trace.oracleGeneral.bin.gz", data: records, err: "could not read"},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(t.TempDir(), tt.name)
+		if err := os.WriteFile(path, tt.data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		options := tt.options
+		if options.Format == "" {
+			options.CSV = defaultCSVOptions()
+		}
+		r, err := openTrace(path, options)
+		if err != nil {
+			if tt.err == "" || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+			}
+			continue
+		}
+		got, err := readAll(r)
+		r.Close()
+		if err != nil || tt.err != "" {
+			t.Errorf("%s: error %v, want %q", tt.name, err, tt.err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: read %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -4,3 +4,31 @@ type Generator interface {
 	Name() string
 	Next() string
 }
+
+// Splitter is a Generator that gives every goroutine its own generator of
+// the same keys, so the keys can be drawn while replaying them instead of
+// in advance.
+type Splitter interface {
+	Generator
+	// Split returns a generator that is independent of the others,
+	// drawing from its own seed.
+	Split(seed int64) Generator
+}
+
+// Request is a single access read from a trace.
+type Request struct {
+	Timestamp uint64
+	Key       string
+	Size      uint32
+	// Op is the operation of the request, if the trace has one.
+	Op string
+	// NextAccess is the index of the next request to Key, -1 if there is none.
+	NextAccess int64
+}
+
+// Reader streams the requests of a trace.
+type Reader interface {
+	// Read reads the next request into r, it returns io.EOF after the last one.
+	Read(r *Request) error
+	Close() error
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/olekukonko/tablewriter"
+)
+
+// windowSpec splits a run into windows of requests requests, or of duration.
+// The zero value does not split runs.
+type windowSpec struct {
+	requests int64
+	duration time.Duration
+}
+
+// parseWindow parses a number of requests, such as 1e5, or a duration,
+// such as 100ms.
+func parseWindow(s string) (windowSpec, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return windowSpec{}, fmt.Errorf("invalid -window %q: must be positive", s)
+		}
+		return windowSpec{duration: d}, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f != float64(int64(f)) || f <= 0 {
+		return windowSpec{}, fmt.Errorf("invalid -window %q: must be a positive number of requests or a duration", s)
+	}
+	return windowSpec{requests: int64(f)}, nil
+}
+
+func (s windowSpec) enabled() bool {
+	return s.requests > 0 || s.duration > 0
+}
+
+func (s windowSpec) String() string {
+	if s.duration > 0 {
+		return s.duration.String()
+	}
+	if s.requests > 0 {
+		return strconv.FormatInt(s.requests, 10) + " requests"
+	}
+	return ""
+}
+
+// Window is the hit rate and the throughput of a window of a run. Requests
+// counts the operations, Hits and Misses the records they read: a YCSB scan
+// is one request of many reads, an update one of none.
+type Window struct {
+	// Start is the time of the window since the start of the run.
+	Start    time.Duration `json:"start_ns"`
+	Duration time.Duration `json:"duration_ns"`
+	Requests int64         `json:"requests"`
+	Hits     int64         `json:"hits"`
+	Misses   int64         `json:"misses"`
+	HitRate  float64       `json:"hit_rate"`
+	QPS      float64       `json:"qps"`
+}
+
+// windowCounts are the requests of a worker in a window, and the hits and
+// misses of their reads. End is the time of its last request, for windows
+// of a number of requests.
+type windowCounts struct {
+	requests     int64
+	hits, misses int64
+	end          time.Duration
+}
+
+// windowRecorder counts the hits and misses of one worker by window.
+// A nil windowRecorder records nothing.
+type windowRecorder struct {
+	spec    windowSpec
+	start   time.Time
+	windows []windowCounts
+	cur     int
+	// n counts the requests until the next clock read, for windows of a duration.
+	n int
+}
+
+// clockEvery is the number of requests between two clock reads of windows
+// of a duration.
+const clockEvery = 64
+
+// newWindowRecorders returns a recorder per worker, nil ones if spec does
+// not split runs. Call startWindows once the run starts.
+func newWindowRecorders(concurrency int, spec windowSpec) []*windowRecorder {
+	recorders := make([]*windowRecorder, concurrency)
+	if !spec.enabled() {
+		return recorders
+	}
+	for i := range recorders {
+		recorders[i] = &windowRecorder{spec: spec, windows: make([]windowCounts, 1)}
+	}
+	return recorders
+}
+
+// startWindows sets the start of the run of every recorder.
+func startWindows(recorders []*windowRecorder, start time.Time) {
+	for _, r := range recorders {
+		if r != nil {
+			r.start = start
+		}
+	}
+}
+
+// add records request i, counted from the end of the warmup, a read.
+func (r *windowRecorder) add(i int64, hit bool) {
+	if hit {
+		r.addOp(i, 1, 0)
+	} else {
+		r.addOp(i, 0, 1)
+	}
+}
+
+// addOp records request i, counted from the end of the warmup, an operation
+// that read hits+misses records, none for a write.
+func (r *windowRecorder) addOp(i int64, hits, misses int64) {
+	if r == nil {
+		return
+	}
+	if r.spec.requests > 0 {
+		if w := int(i / r.spec.requests); w != r.cur {
+			r.windows[r.cur].end = time.Since(r.start)
+			r.move(w)
+		}
+	} else if r.n++; r.n >= clockEvery {
+		r.n = 0
+		r.move(int(time.Since(r.start) / r.spec.duration))
+	}
+	w := &r.windows[r.cur]
+	w.requests++
+	w.hits += hits
+	w.misses += misses
+}
+
+func (r *windowRecorder) move(w int) {
+	for len(r.windows) <= w {
+		r.windows = append(r.windows, windowCounts{})
+	}
+	r.cur = w
+}
+
+// finish records the end of the last window of the worker.
+func (r *windowRecorder) finish() {
+	if r != nil {
+		r.windows[r.cur].end = time.Since(r.start)
+	}
+}
+
+// mergeWindows merges the windows of every worker of a run that lasted
+// elapsed, nil if runs are not split.
+func mergeWindows(recorders []*windowRecorder, elapsed time.Duration) []Window {
+	if recorders[0] == nil {
+		return nil
+	}
+	spec := recorders[0].spec
+	var windows []Window
+	var ends []time.Duration
+	for _, r := range recorders {
+		for i, c := range r.windows {
+			if i == len(windows) {
+				windows = append(windows, Window{})
+				ends = append(ends, 0)
+			}
+			windows[i].Requests += c.requests
+			windows[i].Hits += c.hits
+			windows[i].Misses += c.misses
+			// a window of requests ends with the last worker done with it.
+			ends[i] = max(ends[i], c.end)
+		}
+	}
+
+	for i := range windows {
+		w := &windows[i]
+		if spec.duration > 0 {
+			w.Start = time.Duration(i) * spec.duration
+			w.Duration = min(spec.duration, elapsed-w.Start)
+		} else {
+			if i > 0 {
+				w.Start = windows[i-1].Start + windows[i-1].Duration
+			}
+			w.Duration = max(ends[i]-w.Start, 0)
+		}
+		if w.Hits+w.Misses > 0 {
+			w.HitRate = float64(w.Hits) / float64(w.Hits+w.Misses) * 100
+		}
+		if w.Duration > 0 {
+			w.QPS = float64(w.Requests) / w.Duration.Seconds()
+		}
+	}
+	return windows
+}
+
+// writeWindows prints the hit rate of every window of the results that have
+// them, with a row per window and a column per cache. The QPS of the windows
+// are only written in JSON, to keep the table narrow.
+func (b *Benchmark) writeWindows(w io.Writer) {
+	var results []*BenchmarkResult
+	rows := 0
+	for _, r := range b.Results {
+		if len(r.Windows) > 0 {
+			results = append(results, r)
+			rows = max(rows, len(r.Windows))
+		}
+	}
+	if len(results) == 0 {
+		return
+	}
+
+	headers := []string{"Window"}
+	for _, r := range results {
+		headers = append(headers, r.CacheName)
+	}
+	table := tablewriter.NewWriter(w)
+	for i := 0; i < rows; i++ {
+		row := []string{strconv.Itoa(i)}
+		for _, r := range results {
+			if i >= len(r.Windows) {
+				row = append(row, "-")
+				continue
+			}
+			row = append(row, fmt.Sprintf("%.2f%%", r.Windows[i].HitRate))
+		}
+		table.Append(row)
+	}
+	fmt.Fprintf(w, "\nhit rate by window of %s\n\n", b.Window)
+	table.SetHeader(headers)
+	table.SetBorder(false)
+	table.Render()
+}
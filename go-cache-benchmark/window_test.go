@@ -0,0 +1,173 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseWindow(t *testing.T) {
+	tests := []struct {
+		s    string
+		want windowSpec
+		str  string
+		err  bool
+	}{
+		{s: "1000", want: windowSpec{requests: 1000}, str: "1000 requests"},
+		{s: "1e5", want: windowSpec{requests: 100000}, str: "100000 requests"},
+		{s: "100ms", want: windowSpec{duration: 100 * time.Millisecond}, str: "100ms"},
+		{s: "0", err: true},
+		{s: "-5", err: true},
+		{s: "1.5", err: true},
+		{s: "0s", err: true},
+		{s: "-1s", err: true},
+		{s: "often", err: true},
+	}
+	for _, tt := range tests {
+		spec, err := parseWindow(tt.s)
+		if (err != nil) != tt.err {
+			t.Errorf("parseWindow(%q): error %v", tt.s, err)
+			continue
+		}
+		if spec != tt.want || spec.String() != tt.str || spec.enabled() != !tt.err {
+			t.Errorf("parseWindow(%q) = %+v %q, want %+v %q", tt.s, spec, spec.String(), tt.want, tt.str)
+		}
+	}
+}
+
+func TestWindowRecorder(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		requests    int64
+		// ops are the hits and misses of every request, given to the
+		// workers in turn.
+		ops []windowCounts
+		// want are the requests, hits and misses of every window.
+		want []windowCounts
+	}{
+		{
+			name:        "reads",
+			concurrency: 1,
+			requests:    2,
+			ops:         []windowCounts{{hits: 1}, {misses: 1}, {hits: 1}, {hits: 1}, {misses: 1}},
+			want:        []windowCounts{{requests: 2, hits: 1, misses: 1}, {requests: 2, hits: 2}, {requests: 1, misses: 1}},
+		},
+		{
+			// a scan is one request of many reads, a write one of none.
+			name:        "operations",
+			concurrency: 1,
+			requests:    2,
+			ops:         []windowCounts{{hits: 3, misses: 2}, {}, {}, {misses: 4}},
+			want:        []windowCounts{{requests: 2, hits: 3, misses: 2}, {requests: 2, misses: 4}},
+		},
+		{
+			// the workers share the windows of the requests they replay.
+			name:        "workers",
+			concurrency: 3,
+			requests:    4,
+			ops:         []windowCounts{{hits: 1}, {hits: 1}, {misses: 1}, {hits: 1}, {misses: 1}, {misses: 1}, {hits: 1}, {hits: 1}, {hits: 1}},
+			want:        []windowCounts{{requests: 4, hits: 3, misses: 1}, {requests: 4, hits: 2, misses: 2}, {requests: 1, hits: 1}},
+		},
+	}
+	for _, tt := range tests {
+		recorders := newWindowRecorders(tt.concurrency, windowSpec{requests: tt.requests})
+		startWindows(recorders, time.Now())
+		for i, op := range tt.ops {
+			recorders[i%tt.concurrency].addOp(int64(i), op.hits, op.misses)
+		}
+		for _, r := range recorders {
+			r.finish()
+		}
+		windows := mergeWindows(recorders, time.Second)
+
+		if len(windows) != len(tt.want) {
+			t.Errorf("%s: %d windows, want %d", tt.name, len(windows), len(tt.want))
+			continue
+		}
+		for i, w := range windows {
+			want := tt.want[i]
+			if w.Requests != want.requests || w.Hits != want.hits || w.Misses != want.misses {
+				t.Errorf("%s: window %d of %d requests, %d hits and %d misses, want %d, %d and %d",
+					tt.name, i, w.Requests, w.Hits, w.Misses, want.requests, want.hits, want.misses)
+			}
+			if hitRate := float64(want.hits) / float64(want.hits+want.misses) * 100; want.hits+want.misses > 0 && w.HitRate != hitRate {
+				t.Errorf("%s: window %d hit rate %g, want %g", tt.name, i, w.HitRate, hitRate)
+			}
+			if i > 0 && w.Start != windows[i-1].Start+windows[i-1].Duration {
+				t.Errorf("%s: window %d starts at %v, after %v", tt.name, i, w.Start, windows[i-1].Start+windows[i-1].Duration)
+			}
+		}
+	}
+
+	// runs that are not split have no windows.
+	recorders := newWindowRecorders(2, windowSpec{})
+	recorders[0].add(0, true)
+	recorders[0].finish()
+	if windows := mergeWindows(recorders, time.Second); windows != nil {
+		t.Errorf("windows %+v of a run that is not split", windows)
+	}
+}
+
+func TestMergeWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    windowSpec
+		elapsed time.Duration
+		// workers are the windows of every worker.
+		workers [][]windowCounts
+		want    []Window
+	}{
+		{
+			// a window of requests ends with the last worker done with it.
+			name:    "requests",
+			spec:    windowSpec{requests: 100},
+			elapsed: 3 * time.Second,
+			workers: [][]windowCounts{
+				{{requests: 50, hits: 50, end: time.Second}, {requests: 50, hits: 25, misses: 25, end: 3 * time.Second}},
+				{{requests: 50, misses: 50, end: 2 * time.Second}, {requests: 50, misses: 50, end: 2500 * time.Millisecond}},
+			},
+			want: []Window{
+				{Start: 0, Duration: 2 * time.Second, Requests: 100, Hits: 50, Misses: 50, HitRate: 50, QPS: 50},
+				{Start: 2 * time.Second, Duration: time.Second, Requests: 100, Hits: 25, Misses: 75, HitRate: 25, QPS: 100},
+			},
+		},
+		{
+			// the last window of a duration is cut at the end of the run.
+			name:    "duration",
+			spec:    windowSpec{duration: time.Second},
+			elapsed: 1500 * time.Millisecond,
+			workers: [][]windowCounts{
+				{{requests: 100, hits: 100}, {requests: 10, misses: 10}},
+				{{requests: 100, misses: 100}},
+			},
+			want: []Window{
+				{Start: 0, Duration: time.Second, Requests: 200, Hits: 100, Misses: 100, HitRate: 50, QPS: 200},
+				{Start: time.Second, Duration: 500 * time.Millisecond, Requests: 10, Misses: 10, HitRate: 0, QPS: 20},
+			},
+		},
+		{
+			// writes read nothing, their window has no hit rate.
+			name:    "writes",
+			spec:    windowSpec{requests: 10},
+			elapsed: time.Second,
+			workers: [][]windowCounts{{{requests: 10, end: time.Second}}},
+			want:    []Window{{Duration: time.Second, Requests: 10, QPS: 10}},
+		},
+	}
+	for _, tt := range tests {
+		recorders := make([]*windowRecorder, len(tt.workers))
+		for i, windows := range tt.workers {
+			recorders[i] = &windowRecorder{spec: tt.spec, windows: windows}
+		}
+		windows := mergeWindows(recorders, tt.elapsed)
+		if len(windows) != len(tt.want) {
+			t.Errorf("%s: %d windows, want %d", tt.name, len(windows), len(tt.want))
+			continue
+		}
+		for i := range windows {
+			if windows[i] != tt.want[i] {
+				t.Errorf("%s: window %d is %+v, want %+v", tt.name, i, windows[i], tt.want[i])
+			}
+		}
+	}
+}
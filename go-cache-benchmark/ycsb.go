@@ -0,0 +1,335 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hey-kong/shift/go-cache-benchmark/cache"
+	"github.com/hey-kong/shift/go-cache-benchmark/zipf"
+)
+
+// ycsbOpKind is the type of a YCSB operation.
+type ycsbOpKind uint8
+
+const (
+	ycsbRead ycsbOpKind = iota
+	ycsbUpdate
+	ycsbInsert
+	ycsbScan
+	ycsbReadModifyWrite
+	ycsbOpKinds
+)
+
+func (k ycsbOpKind) String() string {
+	switch k {
+	case ycsbRead:
+		return "read"
+	case ycsbUpdate:
+		return "update"
+	case ycsbInsert:
+		return "insert"
+	case ycsbScan:
+		return "scan"
+	case ycsbReadModifyWrite:
+		return "rmw"
+	}
+	return "unknown"
+}
+
+const (
+	ycsbZipfian = "zipfian"
+	ycsbLatest  = "latest"
+)
+
+// YCSBWorkload is the operation mix of a YCSB core workload,
+// see https://github.com/brianfrankcooper/YCSB/wiki/Core-Workloads.
+type YCSBWorkload struct {
+	Name string
+	// Mix is the fraction of each operation kind.
+	Mix [ycsbOpKinds]float64
+	// Distribution picks the keys: zipfian for scrambled zipfian,
+	// latest for zipfian on the most recent inserts.
+	Distribution string
+}
+
+var ycsbWorkloads = []YCSBWorkload{
+	{Name: "a", Mix: [ycsbOpKinds]float64{ycsbRead: 0.5, ycsbUpdate: 0.5}, Distribution: ycsbZipfian},
+	{Name: "b", Mix: [ycsbOpKinds]float64{ycsbRead: 0.95, ycsbUpdate: 0.05}, Distribution: ycsbZipfian},
+	{Name: "c", Mix: [ycsbOpKinds]float64{ycsbRead: 1}, Distribution: ycsbZipfian},
+	{Name: "d", Mix: [ycsbOpKinds]float64{ycsbRead: 0.95, ycsbInsert: 0.05}, Distribution: ycsbLatest},
+	{Name: "e", Mix: [ycsbOpKinds]float64{ycsbScan: 0.95, ycsbInsert: 0.05}, Distribution: ycsbZipfian},
+	{Name: "f", Mix: [ycsbOpKinds]float64{ycsbRead: 0.5, ycsbReadModifyWrite: 0.5}, Distribution: ycsbZipfian},
+}
+
+// lookupYCSBWorkload returns the YCSB workload named name, a to f.
+func lookupYCSBWorkload(name string) (YCSBWorkload, error) {
+	name = strings.TrimPrefix(strings.ToLower(name), "ycsb-")
+	for _, w := range ycsbWorkloads {
+		if w.Name == name {
+			return w, nil
+		}
+	}
+	names := make([]string, len(ycsbWorkloads))
+	for i, w := range ycsbWorkloads {
+		names[i] = w.Name
+	}
+	return YCSBWorkload{}, fmt.Errorf("unknown YCSB workload %q, available workloads: %s", name, strings.Join(names, ", "))
+}
+
+// ycsbOp is an operation on key, or on n keys from key for a scan.
+type ycsbOp struct {
+	kind ycsbOpKind
+	n    int32
+	key  int64
+}
+
+// ycsbRun is the operation sequence of a YCSB workload, generated in advance
+// to not taint the QPS.
+type ycsbRun struct {
+	workload YCSBWorkload
+	alpha    float64
+	// items is the number of records loaded before the run.
+	items int
+	ops   []ycsbOp
+	// names are the keys of the records, including the inserted ones.
+	names []string
+	// maxScan is the most records read by a scan.
+	maxScan int
+}
+
+// options are the options that change the operations of y, as
+// GeneratorOptions.describe.
+func (y *ycsbRun) options() string {
+	if y.workload.Mix[ycsbScan] == 0 {
+		return ""
+	}
+	return fmt.Sprintf("ycsb-scan=%d", y.maxScan)
+}
+
+// generateYCSB draws total operations of w on items records.
+// Scans read up to maxScan records.
+func generateYCSB(w YCSBWorkload, items, total int, alpha float64, maxScan int, seed int64) *ycsbRun {
+	r := rand.New(rand.NewSource(seed))
+	zrng := rand.New(rand.NewSource(zipf.DeriveSeed(seed, 0)))
+
+	// records are inserted at the end of the key space.
+	records := int64(items)
+	var next func() int64
+	grow := func() {}
+	if w.Distribution == ycsbLatest {
+		gen, err := zipf.NewZipfGenerator(zrng, 0, uint64(items-1), alpha, false)
+		if err != nil {
+			panic(fmt.Errorf("could not create zipf generator: %v", err))
+		}
+		next = func() int64 {
+			return records - 1 - int64(gen.Uint64())
+		}
+		grow = func() {
+			if err := gen.IncrementIMax(1); err != nil {
+				panic(fmt.Errorf("could not grow zipf generator: %v", err))
+			}
+		}
+	} else {
+		// like YCSB, draw over the records and the expected inserts,
+		// and draw again the records not inserted yet.
+		inserts := int(float64(total)*w.Mix[ycsbInsert]*2) + 1
+		gen, err := zipf.NewScrambledZipfGenerator(zrng, 0, uint64(items+inserts-1), alpha, false)
+		if err != nil {
+			panic(fmt.Errorf("could not create zipf generator: %v", err))
+		}
+		next = func() int64 {
+			for {
+				if key := int64(gen.Uint64()); key < records {
+					return key
+				}
+			}
+		}
+	}
+
+	var cumulative [ycsbOpKinds]float64
+	var sum float64
+	for k, f := range w.Mix {
+		sum += f
+		cumulative[k] = sum
+	}
+
+	ops := make([]ycsbOp, total)
+	for i := range ops {
+		u := r.Float64() * sum
+		kind := ycsbOpKind(0)
+		for kind < ycsbOpKinds-1 && u >= cumulative[kind] {
+			kind++
+		}
+
+		op := ycsbOp{kind: kind, n: 1}
+		switch kind {
+		case ycsbInsert:
+			op.key = records
+			records++
+			grow()
+		case ycsbScan:
+			op.key = next()
+			op.n = int32(min(int64(1+r.Intn(maxScan)), records-op.key))
+		default:
+			op.key = next()
+		}
+		ops[i] = op
+	}
+
+	names := make([]string, records)
+	for i := range names {
+		names[i] = strconv.FormatInt(int64(i), 10)
+	}
+	return &ycsbRun{
+		workload: w,
+		alpha:    alpha,
+		items:    items,
+		ops:      ops,
+		names:    names,
+		maxScan:  maxScan,
+	}
+}
+
+// OpResult is the outcome of one kind of operation. Hits and Misses only
+// count the reads of the operation.
+type OpResult struct {
+	Op     string `json:"op"`
+	Count  int64  `json:"count"`
+	Hits   int64  `json:"hits"`
+	Misses int64  `json:"misses"`
+}
+
+func (or *OpResult) hitRate() float64 {
+	return float64(or.Hits) / float64(or.Hits+or.Misses) * 100
+}
+
+func runYCSBBenchmark(y *ycsbRun, cacheMultiplier float64, caches []NewCacheFunc, concurrency int, options runOptions) *Benchmark {
+	b := &Benchmark{
+		ItemSize:            y.items,
+		Workloads:           len(y.ops),
+		CacheSizeMultiplier: cacheMultiplier,
+		ZipfAlpha:           y.alpha,
+		Generator:           "ycsb-" + y.workload.Name,
+		GeneratorOptions:    y.options(),
+		Backend:             options.backend.String(),
+		Warmup:              options.warmupRequests(len(y.ops)),
+		Window:              options.window.String(),
+		Concurrency:         concurrency,
+		Results:             make([]*BenchmarkResult, 0),
+	}
+	for _, newCache := range caches {
+		if r := runYCSB(newCache, y, cacheMultiplier, concurrency, options); r != nil {
+			b.Results = append(b.Results, r)
+		}
+	}
+	b.sortResults()
+	return b
+}
+
+// runYCSB replays the operations of y against a cache-aside cache:
+// reads fill the cache on a miss, updates and inserts write the record
+// through the cache. With a backend, both the misses and the writes wait
+// for it, and every record read or written is timed end to end.
+func runYCSB(newCache NewCacheFunc, y *ycsbRun, cacheSizeMultiplier float64, concurrency int, options runOptions) *BenchmarkResult {
+	warm := options.warmupRequests(len(y.ops))
+	cacheSize := int(float64(y.items) * cacheSizeMultiplier)
+	recorders := newLatencyRecorders(concurrency, options)
+	windows := newWindowRecorders(concurrency, options.window)
+	c := newCache(cacheSize)
+
+	if _, ok := c.(cache.Oracle); ok {
+		fmt.Fprintf(os.Stderr, "skipping %s: YCSB workloads give no next access\n", c.Name())
+		c.Close()
+		return nil
+	}
+
+	// the warmup fills the cache on one goroutine, before the timer.
+	for _, op := range y.ops[:warm] {
+		for _, key := range y.names[op.key : op.key+int64(op.n)] {
+			switch op.kind {
+			case ycsbUpdate, ycsbInsert:
+				c.Set(key)
+			case ycsbReadModifyWrite:
+				recorders[0].warm(c, key, 0)
+				c.Set(key)
+			default:
+				recorders[0].warm(c, key, 0)
+			}
+		}
+	}
+
+	counts := make([][ycsbOpKinds]OpResult, concurrency)
+	// read reads key for worker k.
+	read := func(k int, key string, r *OpResult) {
+		if recorders[k].access(c, key, 0) {
+			r.Hits++
+		} else {
+			r.Misses++
+		}
+	}
+
+	start := time.Now()
+	startWindows(windows, start)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			results := &counts[k]
+			l := recorders[k]
+			for j := warm + k; j < len(y.ops); j += concurrency {
+				op := y.ops[j]
+				r := &results[op.kind]
+				r.Count++
+				hits, misses := r.Hits, r.Misses
+				switch op.kind {
+				case ycsbRead:
+					read(k, y.names[op.key], r)
+				case ycsbUpdate, ycsbInsert:
+					l.set(c, y.names[op.key])
+				case ycsbScan:
+					for _, key := range y.names[op.key : op.key+int64(op.n)] {
+						read(k, key, r)
+					}
+				case ycsbReadModifyWrite:
+					key := y.names[op.key]
+					read(k, key, r)
+					l.set(c, key)
+				}
+				windows[k].addOp(int64(j-warm), r.Hits-hits, r.Misses-misses)
+			}
+			windows[k].finish()
+		}(i)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	result := &BenchmarkResult{
+		CacheName: c.Name(),
+		Duration:  elapsed,
+		Memory:    retained(c),
+		Entries:   cacheSize,
+		Latency:   mergeLatency(recorders),
+		Windows:   mergeWindows(windows, elapsed),
+	}
+	for kind := ycsbOpKind(0); kind < ycsbOpKinds; kind++ {
+		if y.workload.Mix[kind] == 0 {
+			continue
+		}
+		op := &OpResult{Op: kind.String()}
+		for i := range counts {
+			op.Count += counts[i][kind].Count
+			op.Hits += counts[i][kind].Hits
+			op.Misses += counts[i][kind].Misses
+		}
+		result.Hits += op.Hits
+		result.Misses += op.Misses
+		result.Ops = append(result.Ops, op)
+	}
+	return result
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hey-kong/shift/go-cache-benchmark/cache"
+)
+
+func TestGenerateYCSB(t *testing.T) {
+	const items, total, maxScan = 1000, 100000, 10
+	for _, w := range ycsbWorkloads {
+		y := generateYCSB(w, items, total, 0.99, maxScan, 1)
+		if len(y.ops) != total {
+			t.Fatalf("%s: %d operations, want %d", w.Name, len(y.ops), total)
+		}
+
+		var counts [ycsbOpKinds]int
+		records := int64(items)
+		var recent int
+		for i, op := range y.ops {
+			counts[op.kind]++
+			switch op.kind {
+			case ycsbInsert:
+				// inserts append the next record.
+				if op.key != records || op.n != 1 {
+					t.Fatalf("%s: operation %d inserts %d records at %d, want 1 at %d", w.Name, i, op.n, op.key, records)
+				}
+				records++
+			case ycsbScan:
+				if op.n < 1 || op.n > maxScan || op.key < 0 || op.key+int64(op.n) > records {
+					t.Fatalf("%s: operation %d scans %d records at %d of %d", w.Name, i, op.n, op.key, records)
+				}
+			default:
+				if op.n != 1 || op.key < 0 || op.key >= records {
+					t.Fatalf("%s: operation %d reads %d records at %d of %d", w.Name, i, op.n, op.key, records)
+				}
+				if op.key >= records-items/10 {
+					recent++
+				}
+			}
+		}
+		if len(y.names) != int(records) {
+			t.Errorf("%s: %d names, want %d", w.Name, len(y.names), records)
+		}
+
+		for kind, f := range w.Mix {
+			if got := float64(counts[kind]) / total; math.Abs(got-f) > 0.01 {
+				t.Errorf("%s: %.3f of %s, want %.2f", w.Name, got, ycsbOpKind(kind), f)
+			}
+		}
+		// latest reads the 10% most recent records far more than 10% of the time.
+		if reads := total - counts[ycsbInsert] - counts[ycsbScan]; w.Distribution == ycsbLatest && float64(recent) < 0.5*float64(reads) {
+			t.Errorf("%s: %d of %d reads of recent records", w.Name, recent, reads)
+		}
+	}
+}
+
+func TestRunYCSB(t *testing.T) {
+	const items, total = 1000, 20000
+	options := runOptions{warmup: 0.1, window: windowSpec{requests: 1000}}
+	for _, w := range ycsbWorkloads {
+		y := generateYCSB(w, items, total, 0.99, 10, 1)
+		r := runYCSB(cache.NewSieve, y, 0.1, 2, options)
+
+		// every measured operation is counted once, by kind.
+		warm := options.warmupRequests(total)
+		var want [ycsbOpKinds]int64
+		var reads int64
+		for _, op := range y.ops[warm:] {
+			want[op.kind]++
+			switch op.kind {
+			case ycsbRead, ycsbReadModifyWrite:
+				reads++
+			case ycsbScan:
+				reads += int64(op.n)
+			}
+		}
+		var count int64
+		for _, op := range r.Ops {
+			count += op.Count
+			for kind := ycsbOpKind(0); kind < ycsbOpKinds; kind++ {
+				if kind.String() == op.Op && op.Count != want[kind] {
+					t.Errorf("%s: %d %s, want %d", w.Name, op.Count, op.Op, want[kind])
+				}
+			}
+		}
+		if count != total-int64(warm) {
+			t.Errorf("%s: %d operations, want %d", w.Name, count, total-warm)
+		}
+		if r.Hits+r.Misses != reads {
+			t.Errorf("%s: %d records read, want %d", w.Name, r.Hits+r.Misses, reads)
+		}
+
+		// windows count operations, and the records they read.
+		var requests, hits, misses int64
+		for _, window := range r.Windows {
+			requests += window.Requests
+			hits += window.Hits
+			misses += window.Misses
+		}
+		if requests != count || hits != r.Hits || misses != r.Misses {
+			t.Errorf("%s: windows of %d requests, %d hits and %d misses, want %d, %d and %d",
+				w.Name, requests, hits, misses, count, r.Hits, r.Misses)
+		}
+	}
+}
+
+func TestLookupYCSBWorkload(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		err  bool
+	}{
+		{"a", "a", false},
+		{"F", "f", false},
+		{"ycsb-e", "e", false},
+		{"g", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		w, err := lookupYCSBWorkload(tt.name)
+		if (err != nil) != tt.err || w.Name != tt.want {
+			t.Errorf("lookupYCSBWorkload(%q) = %q, %v, want %q", tt.name, w.Name, err, tt.want)
+		}
+	}
+}
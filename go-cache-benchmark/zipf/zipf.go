@@ -32,7 +32,10 @@ type ZipfGenerator struct {
 	iMin  uint64
 	// internally computed values
 	alpha, zeta2, halfPowTheta float64
-	verbose                    bool
+	// hIntegralX1 and s are the parameters of rejection-inversion,
+	// used when theta == 1.
+	hIntegralX1, s float64
+	verbose        bool
 }
 
 // ZipfGeneratorMu holds variables which must be globally synced.
@@ -42,6 +45,9 @@ type ZipfGeneratorMu struct {
 	iMax  uint64
 	eta   float64
 	zetaN float64
+	// hIntegralN is H(iMax-iMin+1.5), used instead of eta and zetaN
+	// when theta == 1.
+	hIntegralN float64
 }
 
 // NewZipfGenerator constructs a new ZipfGenerator with the given parameters.
@@ -52,8 +58,8 @@ func NewZipfGenerator(
 	if iMin > iMax {
 		return nil, fmt.Errorf("iMin %d > iMax %d", iMin, iMax)
 	}
-	if theta < 0.0 || theta == 1.0 {
-		return nil, fmt.Errorf("0 < theta, and theta != 1")
+	if theta < 0.0 {
+		return nil, fmt.Errorf("0 < theta")
 	}
 
 	z := ZipfGenerator{
@@ -68,6 +74,14 @@ func NewZipfGenerator(
 	z.zipfGenMu.mu.Lock()
 	defer z.zipfGenMu.mu.Unlock()
 
+	if theta == 1.0 {
+		// Gray's formulas divide by 1-theta, use rejection-inversion instead.
+		z.hIntegralX1 = hIntegral(1.5, theta) - 1
+		z.s = 2 - hIntegralInverse(hIntegral(2.5, theta)-h(2, theta), theta)
+		z.zipfGenMu.hIntegralN = hIntegral(float64(iMax+1-iMin)+0.5, theta)
+		return &z, nil
+	}
+
 	// Compute hidden parameters
 	zeta2, err := computeZetaFromScratch(2, theta)
 	if err != nil {
@@ -118,28 +132,98 @@ func computeZetaFromScratch(n uint64, theta float64) (float64, error) {
 func (z *ZipfGenerator) Uint64() uint64 {
 	z.zipfGenMu.mu.Lock()
 	defer z.zipfGenMu.mu.Unlock()
-	u := z.zipfGenMu.r.Float64()
-	uz := u * z.zipfGenMu.zetaN
+	return z.draw(&z.zipfGenMu)
+}
+
+// draw draws a value with the RNG and the parameters of mu, without locking.
+func (z *ZipfGenerator) draw(mu *ZipfGeneratorMu) uint64 {
 	var result uint64
-	if uz < 1.0 {
-		result = z.iMin
-	} else if uz < z.halfPowTheta {
-		result = z.iMin + 1
+	if z.theta == 1.0 {
+		result = z.iMin + z.rejectionInversion(mu) - 1
 	} else {
-		spread := float64(z.zipfGenMu.iMax + 1 - z.iMin)
-		result = z.iMin + uint64(int64(spread*math.Pow(z.zipfGenMu.eta*u-z.zipfGenMu.eta+1.0, z.alpha)))
+		u := mu.r.Float64()
+		uz := u * mu.zetaN
+		if uz < 1.0 {
+			result = z.iMin
+		} else if uz < z.halfPowTheta {
+			result = z.iMin + 1
+		} else {
+			spread := float64(mu.iMax + 1 - z.iMin)
+			result = z.iMin + uint64(int64(spread*math.Pow(mu.eta*u-mu.eta+1.0, z.alpha)))
+		}
 	}
 	if z.verbose {
-		fmt.Printf("Uint64[%d, %d] -> %d\n", z.iMin, z.zipfGenMu.iMax, result)
+		fmt.Printf("Uint64[%d, %d] -> %d\n", z.iMin, mu.iMax, result)
 	}
 	return result
 }
 
+// rejectionInversion draws a rank in [1, iMax-iMin+1] by the rejection-inversion
+// method of Hörmann and Derflinger, which supports any theta.
+// See https://github.com/apache/commons-rng/blob/master/commons-rng-sampling/src/main/java/org/apache/commons/rng/sampling/distribution/RejectionInversionZipfSampler.java
+func (z *ZipfGenerator) rejectionInversion(mu *ZipfGeneratorMu) uint64 {
+	n := float64(mu.iMax + 1 - z.iMin)
+	for {
+		u := mu.hIntegralN + mu.r.Float64()*(z.hIntegralX1-mu.hIntegralN)
+		x := hIntegralInverse(u, z.theta)
+		k := math.Floor(x + 0.5)
+		if k < 1 {
+			k = 1
+		} else if k > n {
+			k = n
+		}
+		if k-x <= z.s || u >= hIntegral(k+0.5, z.theta)-h(k, z.theta) {
+			return uint64(k)
+		}
+	}
+}
+
+// h is the probability of x, up to a constant.
+func h(x, theta float64) float64 {
+	return math.Exp(-theta * math.Log(x))
+}
+
+// hIntegral is the integral of h, up to a constant.
+func hIntegral(x, theta float64) float64 {
+	logX := math.Log(x)
+	return helper2((1-theta)*logX) * logX
+}
+
+// hIntegralInverse is the inverse of hIntegral.
+func hIntegralInverse(x, theta float64) float64 {
+	t := x * (1 - theta)
+	if t < -1 {
+		t = -1
+	}
+	return math.Exp(helper1(t) * x)
+}
+
+// helper1 is log1p(x)/x, accurate around 0.
+func helper1(x float64) float64 {
+	if math.Abs(x) > 1e-8 {
+		return math.Log1p(x) / x
+	}
+	return 1 - x*(0.5-x*(1.0/3-0.25*x))
+}
+
+// helper2 is expm1(x)/x, accurate around 0.
+func helper2(x float64) float64 {
+	if math.Abs(x) > 1e-8 {
+		return math.Expm1(x) / x
+	}
+	return 1 + x*0.5*(1+x/3*(1+0.25*x))
+}
+
 // IncrementIMax increments iMax by count and recomputes the internal values
 // that depend on it. It throws an error if the recomputation failed.
 func (z *ZipfGenerator) IncrementIMax(count uint64) error {
 	z.zipfGenMu.mu.Lock()
 	defer z.zipfGenMu.mu.Unlock()
+	if z.theta == 1.0 {
+		z.zipfGenMu.iMax += count
+		z.zipfGenMu.hIntegralN = hIntegral(float64(z.zipfGenMu.iMax+1-z.iMin)+0.5, z.theta)
+		return nil
+	}
 	zetaN, err := computeZetaIncrementally(
 		z.zipfGenMu.iMax+1-z.iMin, z.zipfGenMu.iMax+count+1-z.iMin, z.theta, z.zipfGenMu.zetaN)
 	if err != nil {
@@ -151,3 +235,122 @@ func (z *ZipfGenerator) IncrementIMax(count uint64) error {
 	z.zipfGenMu.zetaN = zetaN
 	return nil
 }
+
+// Local returns a generator of the same distribution for a single goroutine.
+// It draws from its own RNG, seeded by seed, without locking, and keeps the
+// parameters of z at the time of the call: later calls to IncrementIMax on z
+// do not affect it.
+func (z *ZipfGenerator) Local(seed int64) *LocalZipfGenerator {
+	z.zipfGenMu.mu.Lock()
+	defer z.zipfGenMu.mu.Unlock()
+	return &LocalZipfGenerator{
+		z: z,
+		state: ZipfGeneratorMu{
+			r:          rand.New(rand.NewSource(seed)),
+			iMax:       z.zipfGenMu.iMax,
+			eta:        z.zipfGenMu.eta,
+			zetaN:      z.zipfGenMu.zetaN,
+			hIntegralN: z.zipfGenMu.hIntegralN,
+		},
+	}
+}
+
+// LocalZipfGenerator is a ZipfGenerator that is not safe for concurrent use,
+// see ZipfGenerator.Local. Every goroutine gets its own, so draws never wait
+// on a lock.
+type LocalZipfGenerator struct {
+	z     *ZipfGenerator
+	state ZipfGeneratorMu
+	// scrambled values are hashed into [iMin, iMin+items).
+	scrambled bool
+	iMin      uint64
+	items     uint64
+}
+
+// Uint64 draws a new value, see ZipfGenerator.Uint64
+// and ScrambledZipfGenerator.Uint64.
+func (l *LocalZipfGenerator) Uint64() uint64 {
+	v := l.z.draw(&l.state)
+	if l.scrambled {
+		return l.iMin + fnvHash64(v)%l.items
+	}
+	return v
+}
+
+// ScrambledZipfGenerator draws values between iMin and iMax with a Zipf
+// distribution of the popularity, but the popular values are spread over the
+// range instead of being the smallest ones, like YCSB's ScrambledZipfianGenerator.
+// Ranks are drawn from a Zipf distribution and hashed into the range,
+// so a few values collide and others are never drawn.
+type ScrambledZipfGenerator struct {
+	gen   *ZipfGenerator
+	iMin  uint64
+	items uint64
+}
+
+// NewScrambledZipfGenerator constructs a new ScrambledZipfGenerator with the
+// given parameters. It returns an error if the parameters are outside the
+// accepted range.
+func NewScrambledZipfGenerator(
+	rng *rand.Rand, iMin, iMax uint64, theta float64, verbose bool,
+) (*ScrambledZipfGenerator, error) {
+	if iMin > iMax {
+		return nil, fmt.Errorf("iMin %d > iMax %d", iMin, iMax)
+	}
+	// like YCSB, rank over a huge range for the default theta, whose zeta is
+	// precomputed, and over the range itself otherwise.
+	ranks := iMax - iMin
+	if theta == defaultTheta {
+		ranks = defaultIMax - 1
+	}
+	gen, err := NewZipfGenerator(rng, 0, ranks, theta, verbose)
+	if err != nil {
+		return nil, err
+	}
+	return &ScrambledZipfGenerator{
+		gen:   gen,
+		iMin:  iMin,
+		items: iMax - iMin + 1,
+	}, nil
+}
+
+// Uint64 draws a new value between iMin and iMax.
+func (s *ScrambledZipfGenerator) Uint64() uint64 {
+	return s.iMin + fnvHash64(s.gen.Uint64())%s.items
+}
+
+// Local returns a generator of the same distribution for a single goroutine,
+// see ZipfGenerator.Local.
+func (s *ScrambledZipfGenerator) Local(seed int64) *LocalZipfGenerator {
+	l := s.gen.Local(seed)
+	l.scrambled = true
+	l.iMin = s.iMin
+	l.items = s.items
+	return l
+}
+
+// fnvHash64 is the 64-bit FNV-1a hash of the bytes of v, as in YCSB's Utils.fnvhash64.
+func fnvHash64(v uint64) uint64 {
+	const (
+		offsetBasis = 0xCBF29CE484222325
+		prime       = 1099511628211
+	)
+	h := uint64(offsetBasis)
+	for i := 0; i < 8; i++ {
+		h ^= v & 0xff
+		h *= prime
+		v >>= 8
+	}
+	return h
+}
+
+// DeriveSeed derives the seed of the i-th generator from a master seed,
+// so that the generators of a run draw independent sequences that are
+// reproducible from the master seed alone.
+func DeriveSeed(master int64, i int) int64 {
+	// splitmix64
+	z := uint64(master) + uint64(i+1)*0x9E3779B97F4A7C15
+	z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+	z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+	return int64(z ^ (z >> 31))
+}
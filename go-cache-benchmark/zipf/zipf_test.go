@@ -0,0 +1,119 @@
+package zipf
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// checkZipf draws n values from draw and checks the frequency of every value
+// of [iMin, iMax] against a Zipf distribution of exponent 1, within five
+// standard deviations.
+func checkZipf(t *testing.T, name string, draw func() uint64, iMin, iMax uint64, n int) {
+	t.Helper()
+	items := int(iMax - iMin + 1)
+	counts := make([]int, items)
+	for i := 0; i < n; i++ {
+		v := draw()
+		if v < iMin || v > iMax {
+			t.Fatalf("%s: drew %d, out of [%d, %d]", name, v, iMin, iMax)
+		}
+		counts[v-iMin]++
+	}
+
+	var harmonic float64
+	for k := 1; k <= items; k++ {
+		harmonic += 1 / float64(k)
+	}
+	for k := 1; k <= items; k++ {
+		p := 1 / float64(k) / harmonic
+		want := p * float64(n)
+		sigma := math.Sqrt(float64(n) * p * (1 - p))
+		if got := float64(counts[k-1]); math.Abs(got-want) > 5*sigma {
+			t.Errorf("%s: value %d drawn %g times, want %.f within %.f", name, iMin+uint64(k-1), got, want, 5*sigma)
+		}
+	}
+}
+
+func TestZipfThetaOne(t *testing.T) {
+	tests := []struct {
+		iMin, iMax uint64
+	}{
+		{0, 0},
+		{0, 1},
+		{0, 9},
+		{100, 149},
+		{0, 999},
+	}
+	for _, tt := range tests {
+		z, err := NewZipfGenerator(rand.New(rand.NewSource(1)), tt.iMin, tt.iMax, 1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkZipf(t, "ZipfGenerator", z.Uint64, tt.iMin, tt.iMax, 200000)
+		checkZipf(t, "LocalZipfGenerator", z.Local(2).Uint64, tt.iMin, tt.iMax, 200000)
+	}
+}
+
+func TestZipfThetaOneIncrementIMax(t *testing.T) {
+	z, err := NewZipfGenerator(rand.New(rand.NewSource(1)), 0, 9, 1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := z.IncrementIMax(10); err != nil {
+		t.Fatal(err)
+	}
+	checkZipf(t, "ZipfGenerator", z.Uint64, 0, 19, 200000)
+}
+
+func TestDeriveSeed(t *testing.T) {
+	tests := []struct {
+		master int64
+		i      int
+	}{
+		{0, 0},
+		{0, 1},
+		{1, 0},
+		{19931203, 0},
+		{19931203, 15},
+		{-1, 3},
+	}
+	seen := make(map[int64]bool)
+	for _, tt := range tests {
+		seed := DeriveSeed(tt.master, tt.i)
+		if again := DeriveSeed(tt.master, tt.i); again != seed {
+			t.Errorf("DeriveSeed(%d, %d) = %d, then %d", tt.master, tt.i, seed, again)
+		}
+		if seen[seed] {
+			t.Errorf("DeriveSeed(%d, %d) = %d, the seed of another generator", tt.master, tt.i, seed)
+		}
+		seen[seed] = true
+	}
+}
+
+func TestLocalIsReproducible(t *testing.T) {
+	// the generators of a run are reproducible from the master seed alone.
+	draws := func(master int64, i int) []uint64 {
+		z, err := NewScrambledZipfGenerator(rand.New(rand.NewSource(master)), 0, 999, 0.99, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		l := z.Local(DeriveSeed(master, i))
+		values := make([]uint64, 100)
+		for j := range values {
+			values[j] = l.Uint64()
+		}
+		return values
+	}
+	a, b, other := draws(7, 1), draws(7, 1), draws(7, 2)
+	same := true
+	for j := range a {
+		if a[j] != b[j] {
+			t.Fatalf("draw %d of generator 1 of seed 7 = %d, then %d", j, a[j], b[j])
+		}
+		same = same && a[j] == other[j]
+	}
+	if same {
+		t.Errorf("generators 1 and 2 of seed 7 draw the same values")
+	}
+}
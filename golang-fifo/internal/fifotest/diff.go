@@ -0,0 +1,206 @@
+package fifotest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// OpKind is the method of fifo.Cache an Op calls.
+type OpKind uint8
+
+const (
+	OpGet OpKind = iota
+	OpSet
+	OpPeek
+	OpContains
+	OpPurge
+	// OpLen is only in the histories of Record, Diff compares Len after
+	// every operation.
+	OpLen
+)
+
+// Op is a call to a cache. Value is only set for OpSet.
+type Op struct {
+	Kind  OpKind
+	Key   int
+	Value int
+}
+
+func (o Op) String() string {
+	switch o.Kind {
+	case OpGet:
+		return fmt.Sprintf("Get(%d)", o.Key)
+	case OpSet:
+		return fmt.Sprintf("Set(%d, %d)", o.Key, o.Value)
+	case OpPeek:
+		return fmt.Sprintf("Peek(%d)", o.Key)
+	case OpContains:
+		return fmt.Sprintf("Contains(%d)", o.Key)
+	case OpPurge:
+		return "Purge()"
+	case OpLen:
+		return "Len()"
+	}
+	return "unknown"
+}
+
+// Keys is the number of distinct keys of the operations on a cache of size
+// entries, enough to both hit and evict.
+func Keys(size int) int {
+	return 2*size + 2
+}
+
+// Ops decodes data into operations on a cache of size entries, two bytes
+// per operation: the first picks the method, mostly Get and Set, and the
+// second the key. Set values are the index of the operation, so that a
+// stale value is told apart.
+func Ops(data []byte, size int) []Op {
+	keys := Keys(size)
+	ops := make([]Op, 0, len(data)/2)
+	for i := 0; i+1 < len(data); i += 2 {
+		op := Op{Key: int(data[i+1]) % keys}
+		switch k := data[i] % 32; {
+		case k < 14:
+			op.Kind = OpGet
+		case k < 24:
+			op.Kind = OpSet
+			op.Value = len(ops)
+		case k < 27:
+			op.Kind = OpPeek
+		case k < 31:
+			op.Kind = OpContains
+		default:
+			op.Kind = OpPurge
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// invariantChecker is implemented by the caches that validate their
+// internal state.
+type invariantChecker interface {
+	CheckInvariants() error
+}
+
+// Diff replays ops on a cache of newCache and on a reference model of
+// newModel, both of size entries. It returns an error describing the first
+// operation after which they differ: in what the operation returned, in
+// their length, or in the keys they hold. It also returns an error after
+// an operation that breaks the invariants of the cache, if it checks them.
+func Diff(newCache, newModel Constructor, size int, ops []Op) error {
+	cache, model := newCache(size), newModel(size)
+	checker, _ := cache.(invariantChecker)
+	for i, op := range ops {
+		var got, want string
+		switch op.Kind {
+		case OpGet:
+			got, want = outcome(cache.Get(op.Key)), outcome(model.Get(op.Key))
+		case OpSet:
+			cache.Set(op.Key, op.Value)
+			model.Set(op.Key, op.Value)
+		case OpPeek:
+			got, want = outcome(cache.Peek(op.Key)), outcome(model.Peek(op.Key))
+		case OpContains:
+			got, want = fmt.Sprint(cache.Contains(op.Key)), fmt.Sprint(model.Contains(op.Key))
+		case OpPurge:
+			cache.Purge()
+			model.Purge()
+		}
+		if got != want {
+			return fmt.Errorf("op %d %v returned %s, want %s", i, op, got, want)
+		}
+		if checker != nil {
+			if err := checker.CheckInvariants(); err != nil {
+				return fmt.Errorf("after op %d %v: %v", i, op, err)
+			}
+		}
+		if got, want := cache.Len(), model.Len(); got != want {
+			return fmt.Errorf("after op %d %v: Len() = %d, want %d", i, op, got, want)
+		}
+		for k := 0; k < Keys(size); k++ {
+			if got, want := cache.Contains(k), model.Contains(k); got != want {
+				return fmt.Errorf("after op %d %v: Contains(%d) = %v, want %v", i, op, k, got, want)
+			}
+		}
+	}
+	return nil
+}
+
+func outcome(value int, ok bool) string {
+	if !ok {
+		return "miss"
+	}
+	return fmt.Sprintf("hit %d", value)
+}
+
+// Shrink removes operations from ops for as long as fails still holds,
+// first in large chunks then one at a time. The result is minimal: removing
+// any single operation makes fails false.
+func Shrink(ops []Op, fails func([]Op) bool) []Op {
+	for chunk := len(ops) / 2; chunk >= 1; {
+		removed := false
+		for i := 0; i+chunk <= len(ops); {
+			candidate := append(append([]Op(nil), ops[:i]...), ops[i+chunk:]...)
+			if fails(candidate) {
+				ops = candidate
+				removed = true
+			} else {
+				i += chunk
+			}
+		}
+		if !removed {
+			chunk /= 2
+		}
+	}
+	return ops
+}
+
+// maxFuzzSize bounds the size of the caches of Fuzz, small caches evict
+// within a few operations.
+const maxFuzzSize = 32
+
+// Variant is a cache of a policy, under a given configuration, and its
+// reference model.
+type Variant struct {
+	Name     string
+	NewCache Constructor
+	NewModel Constructor
+}
+
+// Fuzz checks that a cache of every variant behaves as its reference model
+// on the operations decoded from the fuzzer's input, see Diff. The input
+// also picks the variant and the size of the cache. A divergence is shrunk
+// to a minimal sequence of operations.
+func Fuzz(f *testing.F, variants ...Variant) {
+	r := rand.New(rand.NewSource(1))
+	for v := range variants {
+		for _, size := range []uint8{0, 1, 9, 15, 31} {
+			for n := 64; n <= 1024; n *= 8 {
+				data := make([]byte, 2*n)
+				r.Read(data)
+				f.Add(uint8(v), size, data)
+			}
+		}
+	}
+
+	f.Fuzz(func(t *testing.T, variant, size uint8, data []byte) {
+		v := variants[int(variant)%len(variants)]
+		n := int(size)%maxFuzzSize + 1
+		ops := Ops(data, n)
+		if err := Diff(v.NewCache, v.NewModel, n, ops); err == nil {
+			return
+		}
+		ops = Shrink(ops, func(ops []Op) bool {
+			return Diff(v.NewCache, v.NewModel, n, ops) != nil
+		})
+		var b strings.Builder
+		for _, op := range ops {
+			fmt.Fprintf(&b, "\n\t%v", op)
+		}
+		t.Fatalf("%s: %v\nminimal sequence on a cache of %d entries:%s",
+			v.Name, Diff(v.NewCache, v.NewModel, n, ops), n, b.String())
+	})
+}
@@ -0,0 +1,40 @@
+package fifotest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOps(t *testing.T) {
+	ops := Ops([]byte{0, 5, 14, 7, 24, 30, 31, 1, 2}, 2)
+	require.Equal(t, []Op{
+		{Kind: OpGet, Key: 5},
+		{Kind: OpSet, Key: 1, Value: 1},
+		{Kind: OpPeek, Key: 0},
+		{Kind: OpPurge, Key: 1},
+	}, ops)
+}
+
+func TestShrink(t *testing.T) {
+	var ops []Op
+	for i := 0; i < 100; i++ {
+		ops = append(ops, Op{Kind: OpSet, Key: i, Value: i})
+	}
+	// fails as long as keys 3, 42 and 97 are all set.
+	fails := func(ops []Op) bool {
+		n := 0
+		for _, op := range ops {
+			if op.Key == 3 || op.Key == 42 || op.Key == 97 {
+				n++
+			}
+		}
+		return n == 3
+	}
+
+	require.Equal(t, []Op{
+		{Kind: OpSet, Key: 3, Value: 3},
+		{Kind: OpSet, Key: 42, Value: 42},
+		{Kind: OpSet, Key: 97, Value: 97},
+	}, Shrink(ops, fails))
+}
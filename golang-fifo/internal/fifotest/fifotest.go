@@ -0,0 +1,257 @@
+// Package fifotest is a conformance suite and a set of benchmarks that every
+// fifo.Cache implementation runs through from its own tests.
+package fifotest
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/hey-kong/shift/golang-fifo"
+	"github.com/stretchr/testify/require"
+)
+
+// Constructor returns an empty cache holding up to size entries.
+type Constructor func(size int) fifo.Cache[int, int]
+
+// Run checks that the caches of newCache follow the fifo.Cache contract.
+func Run(t *testing.T, newCache Constructor) {
+	t.Run("GetAndSet", func(t *testing.T) { testGetAndSet(t, newCache) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newCache) })
+	t.Run("PeekAndContains", func(t *testing.T) { testPeekAndContains(t, newCache) })
+	t.Run("PeekDoesNotPromote", func(t *testing.T) { testPeekDoesNotPromote(t, newCache) })
+	t.Run("Len", func(t *testing.T) { testLen(t, newCache) })
+	t.Run("Purge", func(t *testing.T) { testPurge(t, newCache) })
+	t.Run("Capacity", func(t *testing.T) { testCapacity(t, newCache) })
+	t.Run("Concurrent", func(t *testing.T) { testConcurrent(t, newCache) })
+	t.Run("Linearizable", func(t *testing.T) { testLinearizable(t, newCache) })
+}
+
+// the tests of the semantics use a few keys in a large cache, so that no
+// policy evicts any of them.
+const roomy = 100
+
+func testGetAndSet(t *testing.T, newCache Constructor) {
+	cache := newCache(roomy)
+	_, ok := cache.Get(1)
+	require.False(t, ok)
+
+	for i := 0; i < 10; i++ {
+		cache.Set(i, i*10)
+	}
+	for i := 0; i < 10; i++ {
+		v, ok := cache.Get(i)
+		require.True(t, ok, "key %d", i)
+		require.Equal(t, i*10, v)
+	}
+}
+
+func testUpdate(t *testing.T, newCache Constructor) {
+	cache := newCache(roomy)
+	cache.Set(1, 10)
+	cache.Set(1, 11)
+	v, ok := cache.Get(1)
+	require.True(t, ok)
+	require.Equal(t, 11, v)
+	require.Equal(t, 1, cache.Len())
+}
+
+func testPeekAndContains(t *testing.T, newCache Constructor) {
+	cache := newCache(roomy)
+	_, ok := cache.Peek(1)
+	require.False(t, ok)
+	require.False(t, cache.Contains(1))
+
+	cache.Set(1, 10)
+	v, ok := cache.Peek(1)
+	require.True(t, ok)
+	require.Equal(t, 10, v)
+	require.True(t, cache.Contains(1))
+	require.False(t, cache.Contains(2))
+	require.Equal(t, 1, cache.Len())
+}
+
+// testPeekDoesNotPromote replays the same requests on two caches, one of
+// them also peeking at every key, and checks that both keep the same keys:
+// whatever the policy, Peek and Contains must not change what it evicts.
+func testPeekDoesNotPromote(t *testing.T, newCache Constructor) {
+	const size, keys = 16, 64
+	plain, peeked := newCache(size), newCache(size)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		k := r.Intn(keys)
+		for _, cache := range []fifo.Cache[int, int]{plain, peeked} {
+			if _, ok := cache.Get(k); !ok {
+				cache.Set(k, k)
+			}
+		}
+		for k := 0; k < keys; k++ {
+			peeked.Peek(k)
+			peeked.Contains(k)
+		}
+	}
+
+	require.Equal(t, plain.Len(), peeked.Len())
+	for k := 0; k < keys; k++ {
+		require.Equal(t, plain.Contains(k), peeked.Contains(k), "key %d", k)
+	}
+}
+
+func testLen(t *testing.T, newCache Constructor) {
+	cache := newCache(roomy)
+	require.Equal(t, 0, cache.Len())
+
+	cache.Set(1, 1)
+	require.Equal(t, 1, cache.Len())
+
+	// setting a key again does not add an entry.
+	cache.Set(1, 1)
+	require.Equal(t, 1, cache.Len())
+
+	cache.Set(2, 2)
+	require.Equal(t, 2, cache.Len())
+}
+
+func testPurge(t *testing.T, newCache Constructor) {
+	cache := newCache(roomy)
+	for i := 0; i < 20; i++ {
+		cache.Set(i, i)
+	}
+	cache.Purge()
+	require.Equal(t, 0, cache.Len())
+	for i := 0; i < 20; i++ {
+		require.False(t, cache.Contains(i), "key %d", i)
+	}
+
+	// the cache is usable after a purge.
+	for i := 0; i < 10; i++ {
+		cache.Set(i, i)
+	}
+	require.Equal(t, 10, cache.Len())
+	v, ok := cache.Get(5)
+	require.True(t, ok)
+	require.Equal(t, 5, v)
+}
+
+func testCapacity(t *testing.T, newCache Constructor) {
+	for _, size := range []int{1, 2, 10, 100} {
+		cache := newCache(size)
+		r := rand.New(rand.NewSource(int64(size)))
+		for i := 0; i < 100*size; i++ {
+			k := r.Intn(4 * size)
+			if _, ok := cache.Get(k); !ok {
+				cache.Set(k, k)
+				require.True(t, cache.Contains(k), "size %d, key %d", size, k)
+			}
+			require.LessOrEqual(t, cache.Len(), size, "size %d", size)
+		}
+	}
+}
+
+// testConcurrent runs every operation from several goroutines. It finds
+// data races under -race, and otherwise checks that the cache stays
+// consistent.
+func testConcurrent(t *testing.T, newCache Constructor) {
+	const size, keys = 100, 500
+	cache := newCache(size)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < 10000; i++ {
+				k := r.Intn(keys)
+				switch r.Intn(10) {
+				case 0:
+					if v, ok := cache.Peek(k); ok && v != k*10 {
+						t.Errorf("Peek(%d) = %d, want %d", k, v, k*10)
+					}
+				case 1:
+					cache.Contains(k)
+				case 2:
+					if n := cache.Len(); n > size {
+						t.Errorf("Len() = %d, want at most %d", n, size)
+					}
+				default:
+					v, ok := cache.Get(k)
+					if !ok {
+						cache.Set(k, k*10)
+					} else if v != k*10 {
+						t.Errorf("Get(%d) = %d, want %d", k, v, k*10)
+					}
+				}
+				if seed == 0 && i%5000 == 4999 {
+					cache.Purge()
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+	require.LessOrEqual(t, cache.Len(), size)
+	if checker, ok := cache.(invariantChecker); ok {
+		require.NoError(t, checker.CheckInvariants())
+	}
+}
+
+// benchSize is the capacity of the caches of the benchmarks.
+const benchSize = 1 << 14
+
+// BenchmarkHit measures Get on keys in the cache.
+func BenchmarkHit(b *testing.B, newCache Constructor) {
+	cache := newCache(benchSize)
+	for i := 0; i < benchSize; i++ {
+		cache.Set(i, i)
+	}
+	keys := make([]int, 0, benchSize)
+	for i := 0; i < benchSize; i++ {
+		if cache.Contains(i) {
+			keys = append(keys, i)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cache.Get(keys[i%len(keys)])
+	}
+}
+
+// BenchmarkMiss measures a Get that misses and the Set that follows it,
+// which evicts an entry of the full cache.
+func BenchmarkMiss(b *testing.B, newCache Constructor) {
+	cache := newCache(benchSize)
+	for i := 0; i < benchSize; i++ {
+		cache.Set(i, i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := benchSize + i
+		if _, ok := cache.Get(k); !ok {
+			cache.Set(k, k)
+		}
+	}
+}
+
+// BenchmarkMixed measures a cache-aside workload: keys drawn from a zipf
+// distribution over four times the capacity, set on a miss.
+func BenchmarkMixed(b *testing.B, newCache Constructor) {
+	cache := newCache(benchSize)
+	z := rand.NewZipf(rand.New(rand.NewSource(1)), 1.01, 1, 4*benchSize-1)
+	keys := make([]int, 1<<20)
+	for i := range keys {
+		keys[i] = int(z.Uint64())
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k := keys[i&(len(keys)-1)]
+		if _, ok := cache.Get(k); !ok {
+			cache.Set(k, k)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+package fifotest
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// Call is an operation of a history, with what it returned and when it
+// was called and returned. Times are ticks of a clock shared by the
+// goroutines of the history, so that they order the calls of different
+// goroutines.
+type Call struct {
+	Op        Op
+	Goroutine int
+	// Value and Ok are what Get and Peek returned, Ok what Contains returned
+	// and Value what Len returned.
+	Value int
+	Ok    bool
+	Start int64
+	End   int64
+}
+
+func (c Call) String() string {
+	var ret string
+	switch c.Op.Kind {
+	case OpGet, OpPeek:
+		ret = " = miss"
+		if c.Ok {
+			ret = fmt.Sprintf(" = %d", c.Value)
+		}
+	case OpContains:
+		ret = fmt.Sprintf(" = %v", c.Ok)
+	case OpLen:
+		ret = fmt.Sprintf(" = %d", c.Value)
+	}
+	return fmt.Sprintf("[%d, %d] goroutine %d: %v%s", c.Start, c.End, c.Goroutine, c.Op, ret)
+}
+
+// Record runs calls on a cache of newCache from goroutines goroutines at
+// once, calls of each, and returns the history of the calls sorted by start
+// time. Keys are drawn among keys, and Purge is rare. fifo.Cache has no
+// Remove, Purge is the only call that removes keys.
+func Record(newCache Constructor, size, goroutines, calls, keys int, seed int64) []Call {
+	c := newCache(size)
+	var clock atomic.Int64
+	history := make([][]Call, goroutines)
+	// the goroutines start together, for their calls to overlap.
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for g := range history {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed + int64(g)))
+			<-start
+			for i := 0; i < calls; i++ {
+				call := Call{Goroutine: g, Op: Op{Key: r.Intn(keys)}}
+				switch n := r.Intn(100); {
+				case n < 40:
+					call.Op.Kind = OpGet
+				case n < 75:
+					call.Op.Kind = OpSet
+					// values are unique, so that a stale one is told apart.
+					call.Op.Value = g*calls + i
+				case n < 85:
+					call.Op.Kind = OpPeek
+				case n < 93:
+					call.Op.Kind = OpContains
+				case n < 99:
+					call.Op.Kind = OpLen
+				default:
+					call.Op.Kind = OpPurge
+				}
+
+				call.Start = clock.Add(1)
+				switch call.Op.Kind {
+				case OpGet:
+					call.Value, call.Ok = c.Get(call.Op.Key)
+				case OpSet:
+					c.Set(call.Op.Key, call.Op.Value)
+				case OpPeek:
+					call.Value, call.Ok = c.Peek(call.Op.Key)
+				case OpContains:
+					call.Ok = c.Contains(call.Op.Key)
+				case OpLen:
+					call.Value = c.Len()
+				case OpPurge:
+					c.Purge()
+				}
+				call.End = clock.Add(1)
+				history[g] = append(history[g], call)
+				// interleave the goroutines even on a single core.
+				runtime.Gosched()
+			}
+		}(g)
+	}
+	close(start)
+	wg.Wait()
+
+	var all []Call
+	for _, h := range history {
+		all = append(all, h...)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Start < all[j].Start })
+	return all
+}
+
+// mapModel is the sequential specification of a cache of size entries:
+// a map that may drop any key at any time, as the policy sees fit, but never
+// holds more than size keys. Since the evictions are not observed, the
+// model holds the keys that may still be in the cache: a Set adds its key,
+// and a miss or a Purge removes keys for good, until they are set again.
+type mapModel struct {
+	size int
+	keys map[int]int
+}
+
+// apply applies call to the model and reports whether the call is allowed
+// in its state.
+func (m *mapModel) apply(call Call) bool {
+	op := call.Op
+	switch op.Kind {
+	case OpSet:
+		m.keys[op.Key] = op.Value
+	case OpGet, OpPeek, OpContains:
+		v, ok := m.keys[op.Key]
+		if !call.Ok {
+			delete(m.keys, op.Key)
+			return true
+		}
+		return ok && (op.Kind == OpContains || v == call.Value)
+	case OpLen:
+		return call.Value >= 0 && call.Value <= m.size && call.Value <= len(m.keys)
+	case OpPurge:
+		clear(m.keys)
+	}
+	return true
+}
+
+func (m *mapModel) clone() *mapModel {
+	keys := make(map[int]int, len(m.keys))
+	for k, v := range m.keys {
+		keys[k] = v
+	}
+	return &mapModel{size: m.size, keys: keys}
+}
+
+// state returns a canonical string of the keys of the model.
+func (m *mapModel) state() string {
+	keys := make([]int, 0, len(m.keys))
+	for k := range m.keys {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&b, "%d=%d,", k, m.keys[k])
+	}
+	return b.String()
+}
+
+// Linearizable reports whether history, as returned by Record on a cache of
+// size entries, is linearizable: whether there is an order of its calls,
+// consistent with their start and end times, in which every call returns
+// what it did on a sequential map of size entries, see mapModel.
+//
+// It searches the orders depth first, as Wing and Gong, and skips the
+// prefixes of calls already found to lead to the same state of the model,
+// as Lowe.
+func Linearizable(history []Call, size int) bool {
+	done := make([]bool, len(history))
+	failed := make(map[string]bool)
+	var search func(m *mapModel, left int) bool
+	search = func(m *mapModel, left int) bool {
+		if left == 0 {
+			return true
+		}
+		key := memoKey(done) + "|" + m.state()
+		if failed[key] {
+			return false
+		}
+
+		// a call may go next if no pending call ended before it started.
+		end := int64(-1)
+		for i, call := range history {
+			if !done[i] && (end < 0 || call.End < end) {
+				end = call.End
+			}
+		}
+		for i, call := range history {
+			if done[i] {
+				continue
+			}
+			// history is sorted by start times.
+			if call.Start > end {
+				break
+			}
+			next := m.clone()
+			if !next.apply(call) {
+				continue
+			}
+			done[i] = true
+			ok := search(next, left-1)
+			done[i] = false
+			if ok {
+				return true
+			}
+		}
+		failed[key] = true
+		return false
+	}
+	return search(&mapModel{size: size, keys: make(map[int]int)}, len(history))
+}
+
+func memoKey(done []bool) string {
+	b := make([]byte, (len(done)+7)/8)
+	for i, d := range done {
+		if d {
+			b[i/8] |= 1 << (i % 8)
+		}
+	}
+	return string(b)
+}
+
+// testLinearizable records histories of concurrent calls on caches of
+// newCache and checks that they are linearizable. Run it with -race to
+// also find the data races of the calls.
+func testLinearizable(t *testing.T, newCache Constructor) {
+	const size, goroutines, calls, keys = 4, 4, 30, 8
+	for round := 0; round < 50; round++ {
+		history := Record(newCache, size, goroutines, calls, keys, int64(round*goroutines))
+		if Linearizable(history, size) {
+			continue
+		}
+		var b strings.Builder
+		for _, call := range history {
+			fmt.Fprintf(&b, "\n\t%v", call)
+		}
+		t.Fatalf("round %d: history of a cache of %d entries is not linearizable:%s", round, size, b.String())
+	}
+}
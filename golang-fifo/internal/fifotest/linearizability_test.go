@@ -0,0 +1,44 @@
+package fifotest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinearizable(t *testing.T) {
+	set := func(key, value int, start, end int64) Call {
+		return Call{Op: Op{Kind: OpSet, Key: key, Value: value}, Start: start, End: end}
+	}
+	get := func(key, value int, ok bool, start, end int64) Call {
+		return Call{Op: Op{Kind: OpGet, Key: key}, Value: value, Ok: ok, Start: start, End: end}
+	}
+
+	// the Get overlaps both Sets, it may see either value.
+	require.True(t, Linearizable([]Call{
+		set(1, 10, 1, 2),
+		get(1, 10, true, 3, 8),
+		set(1, 11, 4, 5),
+	}, 4))
+
+	// the Get starts after the second Set, the first value is stale.
+	require.False(t, Linearizable([]Call{
+		set(1, 10, 1, 2),
+		set(1, 11, 3, 4),
+		get(1, 10, true, 5, 6),
+	}, 4))
+
+	// a key evicted, or never set, does not come back without a Set.
+	require.False(t, Linearizable([]Call{
+		set(1, 10, 1, 2),
+		get(1, 0, false, 3, 4),
+		get(1, 10, true, 5, 6),
+	}, 4))
+
+	// Len is at most the capacity.
+	require.False(t, Linearizable([]Call{
+		set(1, 10, 1, 2),
+		set(2, 20, 3, 4),
+		{Op: Op{Kind: OpLen}, Value: 2, Start: 5, End: 6},
+	}, 1))
+}
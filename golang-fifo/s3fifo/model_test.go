@@ -0,0 +1,137 @@
+package s3fifo
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hey-kong/shift/golang-fifo"
+	"github.com/hey-kong/shift/golang-fifo/internal/fifotest"
+)
+
+// model is a slow S3FIFO written with slices, oldest entry first, as the
+// reference of S3FIFO.
+type model[K comparable, V any] struct {
+	size  int
+	small []*entry[K, V]
+	main  []*entry[K, V]
+	// ghost holds the keys evicted from small, up to size of them.
+	ghost []K
+}
+
+func newModel[K comparable, V any](size int) fifo.Cache[K, V] {
+	return &model[K, V]{size: size}
+}
+
+func (m *model[K, V]) find(key K) *entry[K, V] {
+	for _, q := range [][]*entry[K, V]{m.small, m.main} {
+		for _, e := range q {
+			if e.key == key {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+func (m *model[K, V]) Set(key K, value V) {
+	if e := m.find(key); e != nil {
+		e.value = value
+		e.freq = min(e.freq+1, 3)
+		return
+	}
+	for len(m.small)+len(m.main) >= m.size {
+		if len(m.small) > m.size/10 {
+			m.evictFromSmall()
+		} else {
+			m.evictFromMain()
+		}
+	}
+	e := &entry[K, V]{key: key, value: value}
+	if i := slices.Index(m.ghost, key); i >= 0 {
+		m.ghost = slices.Delete(m.ghost, i, i+1)
+		m.main = append(m.main, e)
+	} else {
+		m.small = append(m.small, e)
+	}
+}
+
+// evictFromSmall moves the oldest entries of small hit more than once to
+// main, until it evicts one to the ghost.
+func (m *model[K, V]) evictFromSmall() {
+	for len(m.small) > 0 {
+		e := m.small[0]
+		m.small = m.small[1:]
+		if e.freq <= 1 {
+			m.addGhost(e.key)
+			return
+		}
+		m.main = append(m.main, e)
+		if len(m.main) > m.size/10*9 {
+			m.evictFromMain()
+		}
+	}
+}
+
+// evictFromMain reinserts the oldest entries of main that were hit, one
+// hit less, until it evicts one that was not.
+func (m *model[K, V]) evictFromMain() {
+	for len(m.main) > 0 {
+		e := m.main[0]
+		m.main = m.main[1:]
+		if e.freq == 0 {
+			return
+		}
+		e.freq--
+		m.main = append(m.main, e)
+	}
+}
+
+func (m *model[K, V]) addGhost(key K) {
+	if slices.Contains(m.ghost, key) {
+		return
+	}
+	if len(m.ghost) >= m.size {
+		m.ghost = m.ghost[len(m.ghost)-m.size+1:]
+	}
+	m.ghost = append(m.ghost, key)
+}
+
+func (m *model[K, V]) Get(key K) (value V, ok bool) {
+	e := m.find(key)
+	if e == nil {
+		return value, false
+	}
+	e.freq = min(e.freq+1, 3)
+	if i := slices.Index(m.ghost, key); i >= 0 {
+		m.ghost = slices.Delete(m.ghost, i, i+1)
+	}
+	return e.value, true
+}
+
+func (m *model[K, V]) Contains(key K) bool {
+	return m.find(key) != nil
+}
+
+func (m *model[K, V]) Peek(key K) (value V, ok bool) {
+	e := m.find(key)
+	if e == nil {
+		return value, false
+	}
+	return e.value, true
+}
+
+func (m *model[K, V]) Len() int {
+	return len(m.small) + len(m.main)
+}
+
+func (m *model[K, V]) Purge() {
+	m.small, m.main, m.ghost = nil, nil, nil
+}
+
+func FuzzS3FIFO(f *testing.F) {
+	fifotest.Fuzz(f, fifotest.Variant{
+		Name:     "s3fifo",
+		NewCache: New[int, int],
+		NewModel: newModel[int, int],
+	})
+}
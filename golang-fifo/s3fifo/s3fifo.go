@@ -2,9 +2,11 @@ package s3fifo
 
 import (
 	"container/list"
+	"fmt"
 	"sync"
 
 	"github.com/hey-kong/shift/golang-fifo"
+	"github.com/hey-kong/shift/golang-fifo/trace"
 )
 
 type entry[K comparable, V any] struct {
@@ -24,6 +26,9 @@ type S3FIFO[K comparable, V any] struct {
 	small *list.List
 	main  *list.List
 	ghost *bucketTable[K]
+
+	tracer   trace.Sink[K]
+	traceSeq uint64
 }
 
 func New[K comparable, V any](size int) fifo.Cache[K, V] {
@@ -61,8 +66,14 @@ func (s *S3FIFO[K, V]) Set(key K, value V) {
 	if s.ghost.contains(key) {
 		s.ghost.remove(key)
 		s.items[key] = s.main.PushFront(ent)
+		if s.tracer != nil {
+			s.record(trace.Insert, key, 0, "ghost", "main")
+		}
 	} else {
 		s.items[key] = s.small.PushFront(ent)
+		if s.tracer != nil {
+			s.record(trace.Insert, key, 0, "", "small")
+		}
 	}
 }
 
@@ -102,6 +113,9 @@ func (s *S3FIFO[K, V]) Peek(key K) (value V, ok bool) {
 }
 
 func (s *S3FIFO[K, V]) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
 	return s.small.Len() + s.main.Len()
 }
 
@@ -136,6 +150,9 @@ func (s *S3FIFO[K, V]) evictFromSmall() {
 			// move the entry from the small queue to the main queue
 			s.small.Remove(el)
 			s.items[key] = s.main.PushFront(el.Value)
+			if s.tracer != nil {
+				s.record(trace.Promote, key, el.Value.(*entry[K, V]).freq, "small", "main")
+			}
 
 			if s.main.Len() > mainCacheSize {
 				s.evictFromMain()
@@ -145,6 +162,9 @@ func (s *S3FIFO[K, V]) evictFromSmall() {
 			s.ghost.add(key)
 			evicted = true
 			delete(s.items, key)
+			if s.tracer != nil {
+				s.record(trace.Evict, key, el.Value.(*entry[K, V]).freq, "small", "ghost")
+			}
 		}
 	}
 }
@@ -158,10 +178,83 @@ func (s *S3FIFO[K, V]) evictFromMain() {
 			s.main.Remove(el)
 			s.items[key] = s.main.PushFront(el.Value)
 			el.Value.(*entry[K, V]).freq -= 1
+			if s.tracer != nil {
+				s.record(trace.Requeue, key, el.Value.(*entry[K, V]).freq, "main", "main")
+			}
 		} else {
 			s.main.Remove(el)
 			evicted = true
 			delete(s.items, key)
+			if s.tracer != nil {
+				s.record(trace.Evict, key, 0, "main", "")
+			}
+		}
+	}
+}
+
+// CheckInvariants returns an error if the internal state of the cache is
+// inconsistent. It walks every entry, it is meant for tests and debugging.
+func (s *S3FIFO[K, V]) CheckInvariants() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if n := s.small.Len() + s.main.Len(); n > s.size {
+		return fmt.Errorf("s3fifo: %d entries, capacity %d", n, s.size)
+	}
+	if n := s.small.Len() + s.main.Len(); len(s.items) != n {
+		return fmt.Errorf("s3fifo: %d keys in the map, %d entries in the queues", len(s.items), n)
+	}
+	for _, q := range []struct {
+		name string
+		l    *list.List
+	}{{"small", s.small}, {"main", s.main}} {
+		for e := q.l.Front(); e != nil; e = e.Next() {
+			ent := e.Value.(*entry[K, V])
+			if s.items[ent.key] != e {
+				return fmt.Errorf("s3fifo: key %v of the %s queue maps to another element", ent.key, q.name)
+			}
+			if ent.freq > 3 {
+				return fmt.Errorf("s3fifo: key %v has frequency %d, above 3", ent.key, ent.freq)
+			}
 		}
 	}
+
+	g := s.ghost
+	if g.ll.Len() != len(g.items) {
+		return fmt.Errorf("s3fifo: %d keys in the ghost map, %d in its list", len(g.items), g.ll.Len())
+	}
+	if g.ll.Len() > g.size {
+		return fmt.Errorf("s3fifo: %d ghost keys, capacity %d", g.ll.Len(), g.size)
+	}
+	for e := g.ll.Front(); e != nil; e = e.Next() {
+		key := e.Value.(K)
+		if g.items[key] != e {
+			return fmt.Errorf("s3fifo: ghost key %v maps to another element", key)
+		}
+		if _, ok := s.items[key]; ok {
+			return fmt.Errorf("s3fifo: key %v is both in the cache and in the ghost", key)
+		}
+	}
+	return nil
+}
+
+// SetTracer attaches a sink receiving the eviction-path decisions,
+// nil disables tracing.
+func (s *S3FIFO[K, V]) SetTracer(sink trace.Sink[K]) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tracer = sink
+}
+
+func (s *S3FIFO[K, V]) record(kind trace.Kind, key K, freq byte, from, to string) {
+	s.traceSeq++
+	s.tracer.Record(trace.Event[K]{
+		Seq:  s.traceSeq,
+		Kind: kind,
+		Key:  key,
+		Freq: int(freq),
+		From: from,
+		To:   to,
+	})
 }
@@ -3,6 +3,8 @@ package s3fifo
 import (
 	"testing"
 
+	"github.com/hey-kong/shift/golang-fifo/internal/fifotest"
+	"github.com/hey-kong/shift/golang-fifo/trace"
 	"github.com/stretchr/testify/require"
 )
 
@@ -142,3 +144,51 @@ func TestCleanOnCache(t *testing.T) {
 	}
 	require.Equal(t, 0, cache.Len())
 }
+
+func TestTraceOnCache(t *testing.T) {
+	cache := New[int, int](10)
+	r := trace.NewRecorder[int]()
+	require.True(t, trace.Attach(cache, trace.Sink[int](r)))
+
+	for i := 0; i < 10; i++ {
+		cache.Set(i, i)
+	}
+	// 1 is hit twice and promoted from small to main on eviction.
+	cache.Get(1)
+	cache.Get(1)
+	cache.Set(10, 10)
+	cache.Set(11, 11)
+	// 0 was evicted to the ghost queue and comes back into main.
+	cache.Set(0, 0)
+
+	timeline := trace.Timeline(r.Events(), 0)
+	require.Len(t, timeline, 3)
+	require.Equal(t, trace.Insert, timeline[0].Kind)
+	require.Equal(t, "small", timeline[0].To)
+	require.Equal(t, trace.Evict, timeline[1].Kind)
+	require.Equal(t, "ghost", timeline[1].To)
+	require.Equal(t, trace.Insert, timeline[2].Kind)
+	require.Equal(t, "ghost", timeline[2].From)
+	require.Equal(t, "main", timeline[2].To)
+
+	timeline = trace.Timeline(r.Events(), 1)
+	require.Len(t, timeline, 2)
+	require.Equal(t, trace.Promote, timeline[1].Kind)
+	require.Equal(t, 2, timeline[1].Freq)
+}
+
+func TestConformanceOnS3FIFO(t *testing.T) {
+	fifotest.Run(t, New[int, int])
+}
+
+func BenchmarkHitOnS3FIFO(b *testing.B) {
+	fifotest.BenchmarkHit(b, New[int, int])
+}
+
+func BenchmarkMissOnS3FIFO(b *testing.B) {
+	fifotest.BenchmarkMiss(b, New[int, int])
+}
+
+func BenchmarkMixedOnS3FIFO(b *testing.B) {
+	fifotest.BenchmarkMixed(b, New[int, int])
+}
@@ -0,0 +1,122 @@
+package shift
+
+import (
+	"sync/atomic"
+
+	"github.com/hey-kong/shift/golang-fifo"
+)
+
+// clockEntry holds the key and value of a cache entry of Clock.
+// freq is updated atomically by concurrent Gets under the read lock.
+type clockEntry[K comparable, V any] struct {
+	key   K
+	value V
+	freq  atomic.Uint32
+}
+
+func (e *clockEntry[K, V]) entryKey() K            { return e.key }
+func (e *clockEntry[K, V]) entryValue() V          { return e.value }
+func (e *clockEntry[K, V]) frequency() byte        { return byte(e.freq.Load()) }
+func (e *clockEntry[K, V]) setFrequency(freq byte) { e.freq.Store(uint32(freq)) }
+
+// Clock is a CLOCK-style variant of Shift.
+//
+// A hit only increments the atomic frequency counter of the entry, it never
+// moves the entry within its queue, so Get runs under the read lock.
+// All reordering happens lazily in evict, exactly like in Shift.
+//
+// The only behavioural difference from Shift is that Shift moves an entry to
+// the front of its queue on its first hit, while Clock leaves it in place.
+// A hit entry therefore reaches the tail, and gets reinserted with its aged
+// frequency, a little earlier than in Shift. On Zipf workloads the miss ratio
+// stays within a fraction of a percent of Shift's.
+//
+// Global decay needs the write lock, so DecayEvery and DecayInterval are
+// applied lazily on the next Set once they are due.
+type Clock[K comparable, V any] struct {
+	core[K, V, *clockEntry[K, V]]
+
+	// followings control the operation-driven decay.
+	decayEvery int64
+	ops        atomic.Int64
+	decayDue   atomic.Bool
+}
+
+func NewClock[K comparable, V any](size int) fifo.Cache[K, V] {
+	return NewClockWithConfig[K, V](size, DefaultConfig())
+}
+
+// NewClockWithConfig creates a Clock cache with the given config.
+// Zero fields of config fall back to the values of DefaultConfig.
+func NewClockWithConfig[K comparable, V any](size int, config Config) fifo.Cache[K, V] {
+	s := &Clock[K, V]{decayEvery: int64(config.DecayEvery)}
+	s.init("clock", size, config)
+	return s
+}
+
+func (s *Clock[K, V]) Set(key K, value V) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tick()
+	s.maybeDecay()
+	if e, ok := s.items[key]; ok {
+		s.hit(e.Value.(*clockEntry[K, V]))
+		e.Value.(*clockEntry[K, V]).value = value
+		return
+	}
+
+	s.insert(key, &clockEntry[K, V]{key: key, value: value})
+}
+
+func (s *Clock[K, V]) Get(key K) (value V, ok bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	s.tick()
+	if e, ok := s.items[key]; ok {
+		s.hit(e.Value.(*clockEntry[K, V]))
+		return e.Value.(*clockEntry[K, V]).value, true
+	}
+
+	return
+}
+
+func (s *Clock[K, V]) Purge() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.purge()
+	s.ops.Store(0)
+	s.decayDue.Store(false)
+}
+
+// hit increments the frequency of an entry up to maxFreq.
+func (s *Clock[K, V]) hit(ent *clockEntry[K, V]) {
+	for {
+		freq := ent.freq.Load()
+		if freq >= uint32(s.maxFreq) || ent.freq.CompareAndSwap(freq, freq+1) {
+			return
+		}
+	}
+}
+
+// tick counts an operation and marks the global decay as due.
+// It is safe to call under the read lock.
+func (s *Clock[K, V]) tick() {
+	if s.decayEvery > 0 && s.ops.Add(1)%s.decayEvery == 0 {
+		s.decayDue.Store(true)
+	}
+}
+
+// maybeDecay runs the global decay if it is due.
+// It must be called under the write lock.
+func (s *Clock[K, V]) maybeDecay() {
+	due := s.decayDue.Swap(false)
+	if s.intervalDue() {
+		due = true
+	}
+	if due {
+		s.decay()
+	}
+}
@@ -0,0 +1,216 @@
+package shift
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/hey-kong/shift/golang-fifo"
+	"github.com/hey-kong/shift/golang-fifo/internal/fifotest"
+	"github.com/hey-kong/shift/golang-fifo/trace"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetAndSetOnClock(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+	cache := NewClock[int, int](10)
+
+	for _, v := range items {
+		cache.Set(v, v*10)
+	}
+
+	for _, v := range items {
+		val, ok := cache.Get(v)
+		require.True(t, ok)
+		require.Equal(t, v*10, val)
+	}
+}
+
+func TestLenOnClock(t *testing.T) {
+	cache := NewClock[int, int](10)
+	require.Equal(t, 0, cache.Len())
+
+	cache.Set(1, 1)
+	require.Equal(t, 1, cache.Len())
+
+	// duplicated keys only update the frequency and value
+	cache.Set(1, 1)
+	require.Equal(t, 1, cache.Len())
+
+	cache.Set(2, 2)
+	require.Equal(t, 2, cache.Len())
+}
+
+func TestGetDoesNotReorderOnClock(t *testing.T) {
+	cache := NewClock[int, int](10).(*Clock[int, int])
+	for i := 1; i <= 3; i++ {
+		cache.Set(i, i)
+	}
+
+	cache.Get(1)
+	cache.Get(1)
+	require.Equal(t, 1, cache.queues[0].Back().Value.(*clockEntry[int, int]).key)
+	require.Equal(t, uint32(2), cache.items[1].Value.(*clockEntry[int, int]).freq.Load())
+}
+
+func TestExtremelyHotKeyOnClock(t *testing.T) {
+	cache := NewClockWithConfig[int, int](10, Config{MaxFreq: 3}).(*Clock[int, int])
+	cache.Set(0, 0)
+	for i := 0; i < 1000; i++ {
+		cache.Get(0)
+	}
+	require.Equal(t, uint32(3), cache.items[0].Value.(*clockEntry[int, int]).freq.Load())
+
+	for i := 1; i <= 50; i++ {
+		cache.Set(i, i)
+	}
+	require.True(t, cache.Contains(0))
+}
+
+func TestDecayEveryOnClock(t *testing.T) {
+	cache := NewClockWithConfig[int, int](10, Config{DecayEvery: 10}).(*Clock[int, int])
+	cache.Set(0, 0)
+	for i := 0; i < 9; i++ {
+		cache.Get(0)
+	}
+	require.Equal(t, uint32(9), cache.items[0].Value.(*clockEntry[int, int]).freq.Load())
+
+	// the decay became due on a Get, it runs on the next Set.
+	cache.Set(1, 1)
+	require.Equal(t, uint32(4), cache.items[0].Value.(*clockEntry[int, int]).freq.Load())
+}
+
+func TestPurgeOnClock(t *testing.T) {
+	cache := NewClockWithConfig[int, int](10, Config{DecayEvery: 10}).(*Clock[int, int])
+	// the eviction of 8 requeues 0 to 7 and leaves 9 alone in the eviction
+	// queue, so that insertion shifts to the next queue.
+	for i := 0; i < 10; i++ {
+		cache.Set(i, i)
+	}
+	for i := 0; i < 8; i++ {
+		cache.Get(i)
+	}
+	cache.Set(10, 10)
+	require.True(t, cache.shift)
+	cache.Get(0)
+	require.True(t, cache.decayDue.Load())
+
+	cache.Purge()
+	require.Equal(t, 0, cache.Len())
+	require.False(t, cache.shift)
+	require.Equal(t, int64(0), cache.ops.Load())
+	require.False(t, cache.decayDue.Load())
+
+	// the decay due before the purge does not age the new entries.
+	cache.Set(0, 0)
+	require.Equal(t, cache.queues[0], cache.items[0].List())
+	for i := 0; i < 7; i++ {
+		cache.Get(0)
+	}
+	cache.Set(1, 1)
+	require.Equal(t, uint32(7), cache.items[0].Value.(*clockEntry[int, int]).freq.Load())
+}
+
+func TestMissRatioOfClockIsCloseToShift(t *testing.T) {
+	r := rand.New(rand.NewSource(19931203))
+	zipf := rand.NewZipf(r, 1.01, 1, 100000)
+	trace := make([]uint64, 1000000)
+	for i := range trace {
+		trace[i] = zipf.Uint64()
+	}
+
+	missRatio := func(cache fifo.Cache[uint64, uint64]) float64 {
+		misses := 0
+		for _, k := range trace {
+			if _, ok := cache.Get(k); !ok {
+				misses++
+				cache.Set(k, k)
+			}
+		}
+		return float64(misses) / float64(len(trace))
+	}
+
+	for _, size := range []int{100, 1000, 10000} {
+		shift := missRatio(New[uint64, uint64](size))
+		clock := missRatio(NewClock[uint64, uint64](size))
+		require.InDelta(t, shift, clock, 0.005, "size=%d", size)
+	}
+}
+
+func TestConcurrentAccessOnClock(t *testing.T) {
+	cache := NewClock[int, int](100)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < 10000; i++ {
+				k := r.Intn(500)
+				if _, ok := cache.Get(k); !ok {
+					cache.Set(k, k)
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+	require.LessOrEqual(t, cache.Len(), 100)
+}
+
+func BenchmarkParallelGetOnClock(b *testing.B) {
+	benchmarkParallelGet(b, NewClock[int, int](1000))
+}
+
+func BenchmarkParallelGetOnShift(b *testing.B) {
+	benchmarkParallelGet(b, New[int, int](1000))
+}
+
+func benchmarkParallelGet(b *testing.B, cache fifo.Cache[int, int]) {
+	for i := 0; i < 1000; i++ {
+		cache.Set(i, i)
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			cache.Get(i % 1000)
+			i++
+		}
+	})
+}
+
+func TestTraceOnClock(t *testing.T) {
+	cache := NewClock[int, int](2)
+	r := trace.NewRecorder[int]()
+	require.True(t, trace.Attach(cache, trace.Sink[int](r)))
+
+	cache.Set(1, 1)
+	cache.Set(2, 2)
+	cache.Get(1)
+	cache.Set(3, 3)
+
+	timeline := trace.Timeline(r.Events(), 1)
+	require.Len(t, timeline, 2)
+	require.Equal(t, trace.Requeue, timeline[1].Kind)
+	require.Equal(t, "retention", timeline[1].To)
+
+	timeline = trace.Timeline(r.Events(), 2)
+	require.Equal(t, trace.Evict, timeline[len(timeline)-1].Kind)
+}
+
+func TestConformanceOnClock(t *testing.T) {
+	fifotest.Run(t, NewClock[int, int])
+}
+
+func BenchmarkHitOnClock(b *testing.B) {
+	fifotest.BenchmarkHit(b, NewClock[int, int])
+}
+
+func BenchmarkMissOnClock(b *testing.B) {
+	fifotest.BenchmarkMiss(b, NewClock[int, int])
+}
+
+func BenchmarkMixedOnClock(b *testing.B) {
+	fifotest.BenchmarkMixed(b, NewClock[int, int])
+}
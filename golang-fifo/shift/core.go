@@ -0,0 +1,309 @@
+package shift
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hey-kong/shift/golang-fifo/shift/list"
+	"github.com/hey-kong/shift/golang-fifo/trace"
+)
+
+// node is an entry of the queues of a core, *entry for Shift and
+// *clockEntry for Clock, which only differ in their frequency counter.
+type node[K comparable, V any] interface {
+	entryKey() K
+	entryValue() V
+	frequency() byte
+	setFrequency(freq byte)
+}
+
+// core holds the queues shared by Shift and Clock: insertion into the
+// eviction queue or the next one, the eviction that reinserts the entries
+// hit into the queue of their tier, the rotation of the queues and the
+// global decay. Shift and Clock only differ in how they count a hit and
+// when they decay, so they implement Get and Set on top of it.
+type core[K comparable, V any, E node[K, V]] struct {
+	// name prefixes the errors of CheckInvariants.
+	name   string
+	lock   sync.RWMutex
+	size   int
+	items  map[K]*list.Element
+	queues []*list.List
+	shift  bool
+	tier   func(freq byte, queues int) int
+	age    func(freq byte) byte
+
+	// followings control the frequency counter and its time-driven decay.
+	maxFreq       byte
+	decayInterval time.Duration
+	lastDecay     time.Time
+	now           func() time.Time
+
+	tracer   trace.Sink[K]
+	traceSeq uint64
+}
+
+// init sets up an empty core for size entries with the given config.
+// Zero fields of config fall back to the values of DefaultConfig.
+func (s *core[K, V, E]) init(name string, size int, config Config) {
+	def := DefaultConfig()
+	if config.Queues < 2 {
+		config.Queues = def.Queues
+	}
+	if config.Tier == nil {
+		config.Tier = def.Tier
+	}
+	if config.Age == nil {
+		config.Age = def.Age
+	}
+	if config.MaxFreq == 0 {
+		config.MaxFreq = def.MaxFreq
+	}
+
+	s.name = name
+	s.size = size
+	s.items = make(map[K]*list.Element)
+	s.queues = make([]*list.List, config.Queues)
+	s.shift = false
+	s.tier = config.Tier
+	s.age = config.Age
+	s.maxFreq = config.MaxFreq
+	s.decayInterval = config.DecayInterval
+	s.lastDecay = time.Now()
+	s.now = time.Now
+	for i := range s.queues {
+		s.queues[i] = list.New()
+	}
+}
+
+func (s *core[K, V, E]) Contains(key K) (ok bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	_, ok = s.items[key]
+	return
+}
+
+func (s *core[K, V, E]) Peek(key K) (value V, ok bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if e, ok := s.items[key]; ok {
+		return e.Value.(E).entryValue(), true
+	}
+
+	return
+}
+
+func (s *core[K, V, E]) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.len()
+}
+
+// purge drops every entry and starts over as a new cache: insertion goes
+// to the eviction queue and the time-driven decay restarts. Shift and Clock
+// restart their operation-driven decay. It must be called under the write lock.
+func (s *core[K, V, E]) purge() {
+	s.items = make(map[K]*list.Element)
+	for i := range s.queues {
+		s.queues[i] = list.New()
+	}
+	s.setShift(false)
+	s.lastDecay = s.now()
+}
+
+func (s *core[K, V, E]) len() int {
+	n := 0
+	for _, q := range s.queues {
+		n += q.Len()
+	}
+	return n
+}
+
+// insert adds the entry of a new key, evicting another one if the cache is full.
+func (s *core[K, V, E]) insert(key K, ent E) {
+	if s.len() >= s.size {
+		s.evict()
+	}
+	q := 0
+	if s.shift {
+		q = 1
+	}
+	s.items[key] = s.queues[q].PushFront(ent)
+	if s.tracer != nil {
+		s.record(trace.Insert, key, 0, -1, q)
+	}
+}
+
+// intervalDue reports whether DecayInterval has passed since the last
+// time-driven decay, and restarts the interval if so.
+func (s *core[K, V, E]) intervalDue() bool {
+	if s.decayInterval <= 0 {
+		return false
+	}
+	now := s.now()
+	if now.Sub(s.lastDecay) < s.decayInterval {
+		return false
+	}
+	s.lastDecay = now
+	return true
+}
+
+// decay ages the frequency of every entry in the cache.
+func (s *core[K, V, E]) decay() {
+	if s.tracer != nil {
+		var key K
+		s.record(trace.Decay, key, 0, -1, -1)
+	}
+	for _, e := range s.items {
+		ent := e.Value.(E)
+		ent.setFrequency(s.age(ent.frequency()))
+	}
+}
+
+func (s *core[K, V, E]) evict() {
+	eviction := s.queues[0]
+	// new entries go to the next queue once the eviction queue is short,
+	// which may leave it empty in a cache of a few entries.
+	if eviction.Len() == 0 {
+		s.rotate()
+		eviction = s.queues[0]
+		s.setShift(false)
+	}
+	evicted := false
+	for eviction.Len() > 0 && !evicted {
+		o := eviction.Back()
+		ent := o.Value.(E)
+		key := ent.entryKey()
+		if freq := ent.frequency(); freq > 0 {
+			tier := min(max(s.tier(freq, len(s.queues)), 1), len(s.queues)-1)
+			ent.setFrequency(s.age(freq))
+			s.items[key] = s.queues[tier].PushFront(ent)
+			if s.tracer != nil {
+				s.record(trace.Requeue, key, ent.frequency(), 0, tier)
+			}
+		} else {
+			evicted = true
+			delete(s.items, key)
+			if s.tracer != nil {
+				s.record(trace.Evict, key, 0, 0, -1)
+			}
+		}
+		eviction.Remove(o)
+		if eviction.Len() == 0 {
+			s.rotate()
+			eviction = s.queues[0]
+			s.setShift(false)
+		}
+	}
+
+	// if the eviction queue size is less than 10% (refer to S3FIFO) of total size,
+	// shift insertion to the next queue to protect new entries.
+	if eviction.Len() <= s.size/10 {
+		s.setShift(true)
+	}
+}
+
+func (s *core[K, V, E]) setShift(shift bool) {
+	if s.tracer != nil && s.shift != shift {
+		var key K
+		kind := trace.ShiftOff
+		if shift {
+			kind = trace.ShiftOn
+		}
+		s.record(kind, key, 0, -1, -1)
+	}
+	s.shift = shift
+}
+
+// rotate moves the drained eviction queue to the end, so that queues[1]
+// becomes the eviction queue. Empty queues are skipped.
+func (s *core[K, V, E]) rotate() {
+	for i := 1; i < len(s.queues); i++ {
+		drained := s.queues[0]
+		copy(s.queues, s.queues[1:])
+		s.queues[len(s.queues)-1] = drained
+		if s.tracer != nil {
+			var key K
+			s.record(trace.Rotate, key, 0, 0, len(s.queues)-1)
+		}
+		if s.queues[0].Len() > 0 {
+			return
+		}
+	}
+}
+
+// CheckInvariants returns an error if the internal state of the cache is
+// inconsistent. It walks every entry, it is meant for tests and debugging.
+func (s *core[K, V, E]) CheckInvariants() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if len(s.queues) < 2 {
+		return fmt.Errorf("%s: %d queues, at least 2", s.name, len(s.queues))
+	}
+	if n := s.len(); n > s.size {
+		return fmt.Errorf("%s: %d entries, capacity %d", s.name, n, s.size)
+	}
+	if n := s.len(); len(s.items) != n {
+		return fmt.Errorf("%s: %d keys in the map, %d entries in the queues", s.name, len(s.items), n)
+	}
+	for i, q := range s.queues {
+		for e := q.Front(); e != nil; e = e.Next() {
+			ent := e.Value.(E)
+			key := ent.entryKey()
+			if el := s.items[key]; el != e || el.List() != q {
+				return fmt.Errorf("%s: key %v of queue %d maps to another element", s.name, key, i)
+			}
+			if freq := ent.frequency(); freq > s.maxFreq {
+				return fmt.Errorf("%s: key %v has frequency %d, above %d", s.name, key, freq, s.maxFreq)
+			}
+		}
+	}
+	// insertion only shifts to the next queue once the eviction queue is short,
+	// and the eviction queue does not grow until it rotates.
+	if s.shift && s.queues[0].Len() > s.size/10 {
+		return fmt.Errorf("%s: insertion shifted with %d entries in the eviction queue, above %d", s.name, s.queues[0].Len(), s.size/10)
+	}
+	return nil
+}
+
+// SetTracer attaches a sink receiving the eviction-path decisions,
+// nil disables tracing.
+func (s *core[K, V, E]) SetTracer(sink trace.Sink[K]) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tracer = sink
+}
+
+// record sends an event to the tracer, queues are given by index, -1 for none.
+func (s *core[K, V, E]) record(kind trace.Kind, key K, freq byte, from, to int) {
+	s.traceSeq++
+	s.tracer.Record(trace.Event[K]{
+		Seq:  s.traceSeq,
+		Kind: kind,
+		Key:  key,
+		Freq: int(freq),
+		From: queueName(from, len(s.queues)),
+		To:   queueName(to, len(s.queues)),
+	})
+}
+
+// queueName names a queue in trace events.
+// With the default two queues, they are the eviction and retention queues.
+func queueName(i, queues int) string {
+	switch {
+	case i < 0:
+		return ""
+	case i == 0:
+		return "eviction"
+	case queues == 2:
+		return "retention"
+	default:
+		return "retention" + strconv.Itoa(i)
+	}
+}
@@ -0,0 +1,230 @@
+package shift
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hey-kong/shift/golang-fifo"
+	"github.com/hey-kong/shift/golang-fifo/internal/fifotest"
+)
+
+// model is a slow Shift written with slices, oldest entry first, as the
+// reference of Shift and, with clock set, of Clock. It ignores
+// Config.DecayInterval.
+type model[K comparable, V any] struct {
+	size   int
+	config Config
+	clock  bool
+	queues [][]*entry[K, V]
+	shift  bool
+	ops    int
+	// decayDue is set when the decay of Clock waits for the next Set.
+	decayDue bool
+}
+
+func newModel[K comparable, V any](size int, config Config, clock bool) fifo.Cache[K, V] {
+	def := DefaultConfig()
+	if config.Queues < 2 {
+		config.Queues = def.Queues
+	}
+	if config.Tier == nil {
+		config.Tier = def.Tier
+	}
+	if config.Age == nil {
+		config.Age = def.Age
+	}
+	if config.MaxFreq == 0 {
+		config.MaxFreq = def.MaxFreq
+	}
+	return &model[K, V]{
+		size:   size,
+		config: config,
+		clock:  clock,
+		queues: make([][]*entry[K, V], config.Queues),
+	}
+}
+
+// find returns the queue and the index of the entry of key, -1 if none.
+func (m *model[K, V]) find(key K) (q, i int) {
+	for q, queue := range m.queues {
+		for i, e := range queue {
+			if e.key == key {
+				return q, i
+			}
+		}
+	}
+	return -1, -1
+}
+
+func (m *model[K, V]) len() int {
+	n := 0
+	for _, queue := range m.queues {
+		n += len(queue)
+	}
+	return n
+}
+
+// tick counts an operation, Shift decays as soon as it is due while Clock
+// waits for the next Set.
+func (m *model[K, V]) tick(set bool) {
+	if m.config.DecayEvery > 0 {
+		m.ops++
+		if m.ops%m.config.DecayEvery == 0 {
+			m.decayDue = true
+		}
+	}
+	if m.decayDue && (set || !m.clock) {
+		m.decayDue = false
+		for _, queue := range m.queues {
+			for _, e := range queue {
+				e.freq = m.config.Age(e.freq)
+			}
+		}
+	}
+}
+
+// hit counts a hit on the entry at index i of queue q. Shift moves an
+// entry to the newest end of its queue on its first hit, Clock does not.
+func (m *model[K, V]) hit(q, i int) *entry[K, V] {
+	e := m.queues[q][i]
+	if e.freq == 0 && !m.clock {
+		m.queues[q] = append(slices.Delete(m.queues[q], i, i+1), e)
+	}
+	if e.freq < m.config.MaxFreq {
+		e.freq++
+	}
+	return e
+}
+
+func (m *model[K, V]) Set(key K, value V) {
+	m.tick(true)
+	if q, i := m.find(key); q >= 0 {
+		m.hit(q, i).value = value
+		return
+	}
+	if m.len() >= m.size {
+		m.evict()
+	}
+	q := 0
+	if m.shift {
+		q = 1
+	}
+	m.queues[q] = append(m.queues[q], &entry[K, V]{key: key, value: value})
+}
+
+// evict reinserts the oldest entries of the eviction queue that were hit
+// into the queue of their tier, until it evicts one that was not. The queues
+// rotate whenever the eviction queue drains.
+func (m *model[K, V]) evict() {
+	if len(m.queues[0]) == 0 {
+		m.rotate()
+	}
+	for len(m.queues[0]) > 0 {
+		e := m.queues[0][0]
+		m.queues[0] = m.queues[0][1:]
+		evicted := e.freq == 0
+		if !evicted {
+			tier := min(max(m.config.Tier(e.freq, len(m.queues)), 1), len(m.queues)-1)
+			e.freq = m.config.Age(e.freq)
+			m.queues[tier] = append(m.queues[tier], e)
+		}
+		if len(m.queues[0]) == 0 {
+			m.rotate()
+		}
+		if evicted {
+			break
+		}
+	}
+	// new entries go to the next queue while the eviction queue is short.
+	if len(m.queues[0]) <= m.size/10 {
+		m.shift = true
+	}
+}
+
+// rotate moves the empty eviction queue to the end, until the eviction
+// queue has entries or every queue was tried.
+func (m *model[K, V]) rotate() {
+	for i := 1; i < len(m.queues); i++ {
+		m.queues = append(m.queues[1:], m.queues[0])
+		if len(m.queues[0]) > 0 {
+			break
+		}
+	}
+	m.shift = false
+}
+
+func (m *model[K, V]) Get(key K) (value V, ok bool) {
+	m.tick(false)
+	q, i := m.find(key)
+	if q < 0 {
+		return value, false
+	}
+	return m.hit(q, i).value, true
+}
+
+func (m *model[K, V]) Contains(key K) bool {
+	q, _ := m.find(key)
+	return q >= 0
+}
+
+func (m *model[K, V]) Peek(key K) (value V, ok bool) {
+	q, i := m.find(key)
+	if q < 0 {
+		return value, false
+	}
+	return m.queues[q][i].value, true
+}
+
+func (m *model[K, V]) Len() int {
+	return m.len()
+}
+
+// Purge drops the entries and, like Shift, resets the flag and the count of
+// operations.
+func (m *model[K, V]) Purge() {
+	m.queues = make([][]*entry[K, V], len(m.queues))
+	m.shift = false
+	m.ops = 0
+	m.decayDue = false
+}
+
+// fuzzConfigs are the configs the fuzz tests check Shift and Clock with.
+var fuzzConfigs = []struct {
+	name   string
+	config Config
+}{
+	{"default", DefaultConfig()},
+	{"4-queues", Config{Queues: 4}},
+	{"decay-linear", Config{Queues: 3, Tier: TierLinear, Age: AgeDecrement, MaxFreq: 3, DecayEvery: 50}},
+	{"decay-reset", Config{Queues: 8, Age: AgeReset, DecayEvery: 7}},
+}
+
+// fuzzVariants returns a variant per config of fuzzConfigs, of Clock if
+// clock is set and of Shift otherwise.
+func fuzzVariants(clock bool) []fifotest.Variant {
+	var variants []fifotest.Variant
+	for _, c := range fuzzConfigs {
+		config := c.config
+		variants = append(variants, fifotest.Variant{
+			Name: c.name,
+			NewCache: func(size int) fifo.Cache[int, int] {
+				if clock {
+					return NewClockWithConfig[int, int](size, config)
+				}
+				return NewWithConfig[int, int](size, config)
+			},
+			NewModel: func(size int) fifo.Cache[int, int] {
+				return newModel[int, int](size, config, clock)
+			},
+		})
+	}
+	return variants
+}
+
+func FuzzShift(f *testing.F) {
+	fifotest.Fuzz(f, fuzzVariants(false)...)
+}
+
+func FuzzClock(f *testing.F) {
+	fifotest.Fuzz(f, fuzzVariants(true)...)
+}
@@ -1,12 +1,100 @@
 package shift
 
 import (
-	"sync"
+	"math"
+	"math/bits"
+	"time"
 
 	"github.com/hey-kong/shift/golang-fifo"
 	"github.com/hey-kong/shift/golang-fifo/shift/list"
 )
 
+const (
+	DefaultQueues  = 2
+	DefaultMaxFreq = math.MaxUint8
+)
+
+// Config holds the tunables of a Shift cache.
+type Config struct {
+	// Queues is the number of FIFO queues, at least 2.
+	// queues[0] is the eviction queue and the others retain entries that
+	// were hit before they reached the tail of the eviction queue.
+	// Every time the eviction queue drains, the queues rotate by one,
+	// so an entry reinserted into queues[i] survives i rotations.
+	Queues int
+
+	// Tier returns the queue in [1, queues) that an entry with the given
+	// frequency is reinserted into when it reaches the tail of the eviction queue.
+	Tier func(freq byte, queues int) int
+
+	// Age returns the frequency an entry keeps after being reinserted,
+	// and after a global decay.
+	Age func(freq byte) byte
+
+	// MaxFreq is the value the frequency counter saturates at.
+	// Use 3 for a 2-bit counter as in S3FIFO.
+	MaxFreq byte
+
+	// DecayEvery applies Age to every entry after this many Get and Set
+	// operations. Zero disables operation-driven decay.
+	DecayEvery int
+
+	// DecayInterval applies Age to every entry when this much time has passed
+	// since the last decay. It is checked lazily on Get and Set.
+	// Zero disables time-driven decay.
+	DecayInterval time.Duration
+}
+
+// DefaultConfig returns the original two-queue Shift:
+// one eviction queue, one retention queue and halving on requeue.
+func DefaultConfig() Config {
+	return Config{
+		Queues:  DefaultQueues,
+		Tier:    TierLog2,
+		Age:     AgeHalve,
+		MaxFreq: DefaultMaxFreq,
+	}
+}
+
+// MaxFreqBits returns the MaxFreq of a counter with the given number of bits.
+func MaxFreqBits(n int) byte {
+	return byte(1<<min(max(n, 1), 8) - 1)
+}
+
+// TierLog2 places an entry in the queue given by the bit length of its
+// frequency, so 1 goes to queues[1], 2-3 to queues[2], 4-7 to queues[3], etc.
+func TierLog2(freq byte, queues int) int {
+	return min(max(bits.Len8(freq), 1), queues-1)
+}
+
+// TierLinear places an entry with frequency f in queues[f].
+func TierLinear(freq byte, queues int) int {
+	return min(max(int(freq), 1), queues-1)
+}
+
+// AgeHalve halves the frequency.
+func AgeHalve(freq byte) byte {
+	return freq / 2
+}
+
+// AgeDecrement decreases the frequency by one, like S3FIFO's main queue.
+func AgeDecrement(freq byte) byte {
+	if freq == 0 {
+		return 0
+	}
+	return freq - 1
+}
+
+// AgeReset forgets the frequency, an entry must be hit again to be retained.
+func AgeReset(freq byte) byte {
+	return 0
+}
+
+// AgeKeep never ages the frequency.
+func AgeKeep(freq byte) byte {
+	return freq
+}
+
 // entry holds the key and value of a cache entry.
 type entry[K comparable, V any] struct {
 	key   K
@@ -14,125 +102,87 @@ type entry[K comparable, V any] struct {
 	freq  byte
 }
 
+func (e *entry[K, V]) entryKey() K            { return e.key }
+func (e *entry[K, V]) entryValue() V          { return e.value }
+func (e *entry[K, V]) frequency() byte        { return e.freq }
+func (e *entry[K, V]) setFrequency(freq byte) { e.freq = freq }
+
 type Shift[K comparable, V any] struct {
-	lock      sync.RWMutex
-	size      int
-	items     map[K]*list.Element
-	eviction  *list.List
-	retention *list.List
-	shift     bool
+	core[K, V, *entry[K, V]]
+
+	// followings control the operation-driven decay.
+	decayEvery int
+	ops        int
 }
 
 func New[K comparable, V any](size int) fifo.Cache[K, V] {
-	return &Shift[K, V]{
-		size:      size,
-		items:     make(map[K]*list.Element),
-		eviction:  list.New(),
-		retention: list.New(),
-		shift:     false,
-	}
+	return NewWithConfig[K, V](size, DefaultConfig())
+}
+
+// NewWithConfig creates a Shift cache with the given config.
+// Zero fields of config fall back to the values of DefaultConfig.
+func NewWithConfig[K comparable, V any](size int, config Config) fifo.Cache[K, V] {
+	s := &Shift[K, V]{decayEvery: config.DecayEvery}
+	s.init("shift", size, config)
+	return s
 }
 
 func (s *Shift[K, V]) Set(key K, value V) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
 
+	s.tick()
 	if e, ok := s.items[key]; ok {
-		if e.List() == s.eviction && e.Value.(*entry[K, V]).freq == 0 {
-			s.eviction.MoveToFront(e)
-		}
-		if e.List() == s.retention && e.Value.(*entry[K, V]).freq == 0 {
-			s.retention.MoveToFront(e)
-		}
-		e.Value.(*entry[K, V]).freq += 1
+		s.hit(e)
 		e.Value.(*entry[K, V]).value = value
 		return
 	}
 
-	if s.eviction.Len()+s.retention.Len() >= s.size {
-		s.evict()
-	}
-	e := &entry[K, V]{key: key, value: value}
-	if s.shift {
-		s.items[key] = s.retention.PushFront(e)
-	} else {
-		s.items[key] = s.eviction.PushFront(e)
-	}
+	s.insert(key, &entry[K, V]{key: key, value: value})
 }
 
 func (s *Shift[K, V]) Get(key K) (value V, ok bool) {
 	s.lock.Lock()
 	defer s.lock.Unlock()
+	s.tick()
 	if e, ok := s.items[key]; ok {
-		if e.List() == s.eviction && e.Value.(*entry[K, V]).freq == 0 {
-			s.eviction.MoveToFront(e)
-		}
-		if e.List() == s.retention && e.Value.(*entry[K, V]).freq == 0 {
-			s.retention.MoveToFront(e)
-		}
-		e.Value.(*entry[K, V]).freq += 1
+		s.hit(e)
 		return e.Value.(*entry[K, V]).value, true
 	}
 
 	return
 }
 
-func (s *Shift[K, V]) Contains(key K) (ok bool) {
-	s.lock.RLock()
-	defer s.lock.RUnlock()
-	_, ok = s.items[key]
-	return
-}
-
-func (s *Shift[K, V]) Peek(key K) (value V, ok bool) {
-	s.lock.RLock()
-	defer s.lock.RUnlock()
-
-	if e, ok := s.items[key]; ok {
-		return e.Value.(*entry[K, V]).value, true
-	}
+func (s *Shift[K, V]) Purge() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
 
-	return
+	s.purge()
+	s.ops = 0
 }
 
-func (s *Shift[K, V]) Len() int {
-	s.lock.RLock()
-	defer s.lock.RUnlock()
-
-	return s.eviction.Len() + s.retention.Len()
+// hit moves an entry that was not hit yet to the front of its queue,
+// and increments its frequency up to maxFreq.
+func (s *Shift[K, V]) hit(e *list.Element) {
+	ent := e.Value.(*entry[K, V])
+	if ent.freq == 0 {
+		e.List().MoveToFront(e)
+	}
+	if ent.freq < s.maxFreq {
+		ent.freq += 1
+	}
 }
 
-func (s *Shift[K, V]) Purge() {
-	s.lock.Lock()
-	defer s.lock.Unlock()
-
-	s.items = make(map[K]*list.Element)
-	s.eviction = list.New()
-	s.retention = list.New()
-}
-
-func (s *Shift[K, V]) evict() {
-	evicted := false
-	for s.eviction.Len() > 0 && !evicted {
-		o := s.eviction.Back()
-		key := o.Value.(*entry[K, V]).key
-		if o.Value.(*entry[K, V]).freq > 0 {
-			o.Value.(*entry[K, V]).freq /= 2
-			s.items[o.Value.(*entry[K, V]).key] = s.retention.PushFront(o.Value)
-		} else {
-			evicted = true
-			delete(s.items, key)
-		}
-		s.eviction.Remove(o)
-		if s.eviction.Len() == 0 {
-			s.eviction, s.retention = s.retention, s.eviction
-			s.shift = false
+// tick counts an operation and runs the global decay when it is due.
+func (s *Shift[K, V]) tick() {
+	if s.decayEvery > 0 {
+		s.ops++
+		if s.ops >= s.decayEvery {
+			s.ops = 0
+			s.decay()
 		}
 	}
-
-	// if the eviction queue size is less than 10% (refer to S3FIFO) of total size,
-	// shift insertion to the retention queue to protect new entries.
-	if s.eviction.Len() <= s.size/10 {
-		s.shift = true
+	if s.intervalDue() {
+		s.decay()
 	}
 }
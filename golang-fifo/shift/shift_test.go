@@ -1,8 +1,14 @@
 package shift
 
 import (
+	"fmt"
+	"math/rand"
 	"testing"
+	"time"
 
+	"github.com/hey-kong/shift/golang-fifo"
+	"github.com/hey-kong/shift/golang-fifo/internal/fifotest"
+	"github.com/hey-kong/shift/golang-fifo/trace"
 	"github.com/stretchr/testify/require"
 )
 
@@ -43,3 +49,334 @@ func TestLenOnShift(t *testing.T) {
 	cache.Set(2, 2)
 	require.Equal(t, 2, cache.Len())
 }
+
+func TestLenWithinSizeOnMultiQueueShift(t *testing.T) {
+	for _, queues := range []int{2, 3, 4, 8} {
+		cache := NewWithConfig[int, int](10, Config{Queues: queues})
+		for i := 0; i < 100; i++ {
+			cache.Set(i, i)
+			cache.Get(i - i%3)
+			require.LessOrEqual(t, cache.Len(), 10)
+		}
+	}
+}
+
+func TestTierOnMultiQueueShift(t *testing.T) {
+	cache := NewWithConfig[int, int](4, Config{Queues: 4, Tier: TierLinear}).(*Shift[int, int])
+	for i := 1; i <= 4; i++ {
+		cache.Set(i, i)
+	}
+	for i := 0; i < 3; i++ {
+		cache.Get(1)
+	}
+	cache.Get(2)
+
+	// 3 and 4 were never hit and are evicted first.
+	cache.Set(5, 5)
+	cache.Set(6, 6)
+	require.False(t, cache.Contains(3))
+	require.False(t, cache.Contains(4))
+
+	// 1 and 2 reach the tail and are reinserted by their frequency.
+	cache.Set(7, 7)
+	require.False(t, cache.Contains(5))
+	require.Equal(t, cache.queues[3], cache.items[1].List())
+	require.Equal(t, byte(1), cache.items[1].Value.(*entry[int, int]).freq)
+	require.Equal(t, cache.queues[1], cache.items[2].List())
+	require.Equal(t, byte(0), cache.items[2].Value.(*entry[int, int]).freq)
+}
+
+func TestDefaultConfigOnShift(t *testing.T) {
+	cache := NewWithConfig[int, int](10, Config{}).(*Shift[int, int])
+	require.Len(t, cache.queues, DefaultQueues)
+	require.Equal(t, 2, TierLog2(3, 4))
+	require.Equal(t, 3, TierLog2(200, 4))
+	require.Equal(t, 1, TierLinear(1, 4))
+	require.Equal(t, byte(3), AgeHalve(7))
+}
+
+func TestExtremelyHotKeyOnShift(t *testing.T) {
+	cache := New[int, int](10).(*Shift[int, int])
+	cache.Set(0, 0)
+
+	// 256 hits used to wrap the counter back to zero.
+	for i := 0; i < 256; i++ {
+		_, ok := cache.Get(0)
+		require.True(t, ok)
+	}
+	require.Equal(t, byte(DefaultMaxFreq), cache.items[0].Value.(*entry[int, int]).freq)
+
+	// the hot key must survive a long scan of one-hit wonders.
+	for i := 1; i <= 50; i++ {
+		cache.Set(i, i)
+	}
+	require.True(t, cache.Contains(0))
+}
+
+func TestMaxFreqOnShift(t *testing.T) {
+	cache := NewWithConfig[int, int](10, Config{MaxFreq: 3}).(*Shift[int, int])
+	cache.Set(0, 0)
+	for i := 0; i < 10; i++ {
+		cache.Get(0)
+	}
+	require.Equal(t, byte(3), cache.items[0].Value.(*entry[int, int]).freq)
+
+	require.Equal(t, byte(3), MaxFreqBits(2))
+	require.Equal(t, byte(255), MaxFreqBits(8))
+}
+
+func TestDecayEveryOnShift(t *testing.T) {
+	cache := NewWithConfig[int, int](10, Config{DecayEvery: 10}).(*Shift[int, int])
+	cache.Set(0, 0)
+	for i := 0; i < 8; i++ {
+		cache.Get(0)
+	}
+	require.Equal(t, byte(8), cache.items[0].Value.(*entry[int, int]).freq)
+
+	// the 10th operation halves every counter before counting its own hit.
+	cache.Get(0)
+	require.Equal(t, byte(5), cache.items[0].Value.(*entry[int, int]).freq)
+}
+
+func TestDecayIntervalOnShift(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewWithConfig[int, int](10, Config{DecayInterval: time.Second, Age: AgeReset}).(*Shift[int, int])
+	cache.now = func() time.Time { return now }
+	cache.lastDecay = now
+
+	cache.Set(0, 0)
+	cache.Get(0)
+	cache.Get(0)
+	require.Equal(t, byte(2), cache.items[0].Value.(*entry[int, int]).freq)
+
+	now = now.Add(time.Second)
+	cache.Peek(0)
+	require.Equal(t, byte(2), cache.items[0].Value.(*entry[int, int]).freq)
+	cache.Get(0)
+	require.Equal(t, byte(1), cache.items[0].Value.(*entry[int, int]).freq)
+}
+
+func TestPurgeOnShift(t *testing.T) {
+	now := time.Unix(0, 0)
+	cache := NewWithConfig[int, int](10, Config{DecayEvery: 25, DecayInterval: time.Second}).(*Shift[int, int])
+	cache.now = func() time.Time { return now }
+	cache.lastDecay = now
+
+	// 8 and 9 are evicted first, then the eviction of 10 requeues 0 to 7
+	// and leaves 11 alone in the eviction queue, so that insertion shifts
+	// to the next queue.
+	for i := 0; i < 10; i++ {
+		cache.Set(i, i)
+	}
+	for i := 0; i < 8; i++ {
+		cache.Get(i)
+	}
+	for i := 10; i < 13; i++ {
+		cache.Set(i, i)
+	}
+	require.True(t, cache.shift)
+	require.Equal(t, 21, cache.ops)
+
+	now = now.Add(time.Second / 2)
+	cache.Purge()
+	require.Equal(t, 0, cache.Len())
+	require.False(t, cache.shift)
+	require.Equal(t, 0, cache.ops)
+	require.Equal(t, now, cache.lastDecay)
+
+	// the decay restarts: neither a second since the last one nor 25
+	// operations since the first one decay the new entries.
+	cache.Set(0, 0)
+	require.Equal(t, cache.queues[0], cache.items[0].List())
+	for i := 0; i < 8; i++ {
+		cache.Get(0)
+	}
+	now = now.Add(time.Second / 2)
+	cache.Get(0)
+	require.Equal(t, byte(9), cache.items[0].Value.(*entry[int, int]).freq)
+}
+
+func TestAgeFunctionsOnShift(t *testing.T) {
+	require.Equal(t, byte(4), AgeHalve(9))
+	require.Equal(t, byte(8), AgeDecrement(9))
+	require.Equal(t, byte(0), AgeDecrement(0))
+	require.Equal(t, byte(0), AgeReset(9))
+	require.Equal(t, byte(9), AgeKeep(9))
+}
+
+// BenchmarkMissRatioOnShift reports the miss ratio of the counter and aging
+// options on a Zipf workload with 1% of the keys cached.
+func BenchmarkMissRatioOnShift(b *testing.B) {
+	configs := []struct {
+		name   string
+		config Config
+	}{
+		{"default", DefaultConfig()},
+		{"max-freq-3", Config{MaxFreq: 3}},
+		{"max-freq-15", Config{MaxFreq: 15}},
+		{"age-decrement", Config{Age: AgeDecrement}},
+		{"age-reset", Config{Age: AgeReset}},
+		{"decay-every-size", Config{DecayEvery: 1000}},
+		{"decay-every-10x-size", Config{DecayEvery: 10000}},
+	}
+	benchmarkMissRatio(b, configs)
+}
+
+// BenchmarkMissRatioOnMultiQueueShift reports the miss ratio of 3 and 4
+// queues, with each tier function, on the workload of BenchmarkMissRatioOnShift.
+func BenchmarkMissRatioOnMultiQueueShift(b *testing.B) {
+	configs := []struct {
+		name   string
+		config Config
+	}{
+		{"queues-2", Config{Queues: 2}},
+		{"queues-3-tier-log2", Config{Queues: 3, Tier: TierLog2}},
+		{"queues-3-tier-linear", Config{Queues: 3, Tier: TierLinear}},
+		{"queues-4-tier-log2", Config{Queues: 4, Tier: TierLog2}},
+		{"queues-4-tier-linear", Config{Queues: 4, Tier: TierLinear}},
+	}
+	benchmarkMissRatio(b, configs)
+}
+
+// benchmarkMissRatio replays a Zipf workload with 1% of the keys cached
+// on Shift with every config, and reports its miss ratio.
+func benchmarkMissRatio(b *testing.B, configs []struct {
+	name   string
+	config Config
+}) {
+	const keys, size = 100000, 1000
+	r := rand.New(rand.NewSource(19931203))
+	zipf := rand.NewZipf(r, 1.01, 1, keys)
+	trace := make([]uint64, 1000000)
+	for i := range trace {
+		trace[i] = zipf.Uint64()
+	}
+
+	for _, c := range configs {
+		b.Run(fmt.Sprintf("config=%s", c.name), func(b *testing.B) {
+			var misses int
+			for i := 0; i < b.N; i++ {
+				cache := NewWithConfig[uint64, uint64](size, c.config)
+				misses = 0
+				for _, k := range trace {
+					if _, ok := cache.Get(k); !ok {
+						misses++
+						cache.Set(k, k)
+					}
+				}
+			}
+			b.ReportMetric(float64(misses)/float64(len(trace)), "miss-ratio")
+		})
+	}
+}
+
+func TestTraceOnShift(t *testing.T) {
+	cache := NewWithConfig[int, int](4, Config{Queues: 4, Tier: TierLinear})
+	r := trace.NewRecorder[int]()
+	require.True(t, trace.Attach(cache, trace.Sink[int](r)))
+
+	for i := 1; i <= 4; i++ {
+		cache.Set(i, i)
+	}
+	for i := 0; i < 3; i++ {
+		cache.Get(1)
+	}
+	cache.Get(2)
+	for i := 5; i <= 7; i++ {
+		cache.Set(i, i)
+	}
+
+	timeline := trace.Timeline(r.Events(), 1)
+	require.Len(t, timeline, 2)
+	require.Equal(t, trace.Insert, timeline[0].Kind)
+	require.Equal(t, "eviction", timeline[0].To)
+	require.Equal(t, trace.Requeue, timeline[1].Kind)
+	require.Equal(t, 1, timeline[1].Freq)
+	require.Equal(t, "retention3", timeline[1].To)
+
+	timeline = trace.Timeline(r.Events(), 3)
+	require.Len(t, timeline, 2)
+	require.Equal(t, trace.Evict, timeline[1].Kind)
+
+	// detaching the tracer stops recording.
+	trace.Attach(cache, nil)
+	cache.Set(8, 8)
+	require.Len(t, trace.Timeline(r.Events(), 8), 0)
+}
+
+func TestTraceRotateOnShift(t *testing.T) {
+	cache := New[int, int](2)
+	r := trace.NewRecorder[int]()
+	trace.Attach(cache, trace.Sink[int](r))
+
+	cache.Set(1, 1)
+	cache.Set(2, 2)
+	cache.Get(1)
+	cache.Get(2)
+	cache.Set(3, 3)
+
+	var kinds []trace.Kind
+	for _, e := range r.Events() {
+		kinds = append(kinds, e.Kind)
+	}
+	require.Equal(t, []trace.Kind{
+		trace.Insert, trace.Insert,
+		trace.Requeue, trace.Requeue, trace.Rotate, trace.Evict,
+		trace.Insert,
+	}, kinds)
+
+	// the shift flag flips on and off alternately.
+	cache = New[int, int](100)
+	r.Reset()
+	trace.Attach(cache, trace.Sink[int](r))
+	zipf := rand.NewZipf(rand.New(rand.NewSource(1)), 1.01, 1, 1000)
+	for i := 0; i < 10000; i++ {
+		k := int(zipf.Uint64())
+		if _, ok := cache.Get(k); !ok {
+			cache.Set(k, k)
+		}
+	}
+	var flips []trace.Kind
+	for _, e := range r.Events() {
+		if e.Kind == trace.ShiftOn || e.Kind == trace.ShiftOff {
+			flips = append(flips, e.Kind)
+		}
+	}
+	require.NotEmpty(t, flips)
+	require.Equal(t, trace.ShiftOn, flips[0])
+	for i := 1; i < len(flips); i++ {
+		require.NotEqual(t, flips[i-1], flips[i])
+	}
+}
+
+func TestConformanceOnShift(t *testing.T) {
+	fifotest.Run(t, New[int, int])
+}
+
+func BenchmarkHitOnShift(b *testing.B) {
+	fifotest.BenchmarkHit(b, New[int, int])
+}
+
+func BenchmarkMissOnShift(b *testing.B) {
+	fifotest.BenchmarkMiss(b, New[int, int])
+}
+
+func BenchmarkMixedOnShift(b *testing.B) {
+	fifotest.BenchmarkMixed(b, New[int, int])
+}
+
+func TestConformanceOnMultiQueueShift(t *testing.T) {
+	fifotest.Run(t, func(size int) fifo.Cache[int, int] {
+		return NewWithConfig[int, int](size, Config{Queues: 4, DecayEvery: 1000})
+	})
+}
+
+func BenchmarkMixedOnMultiQueueShift(b *testing.B) {
+	for _, queues := range []int{3, 4} {
+		b.Run(fmt.Sprintf("queues=%d", queues), func(b *testing.B) {
+			fifotest.BenchmarkMixed(b, func(size int) fifo.Cache[int, int] {
+				return NewWithConfig[int, int](size, Config{Queues: queues, Tier: TierLog2})
+			})
+		})
+	}
+}
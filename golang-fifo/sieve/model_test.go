@@ -0,0 +1,96 @@
+package sieve
+
+import (
+	"testing"
+
+	"github.com/hey-kong/shift/golang-fifo"
+	"github.com/hey-kong/shift/golang-fifo/internal/fifotest"
+)
+
+// model is a slow SIEVE written with a slice, oldest entry first, as the
+// reference of Sieve.
+type model[K comparable, V any] struct {
+	size    int
+	entries []*entry[K, V]
+	// hand is the index of the next entry to check, -1 for the oldest.
+	hand int
+}
+
+func newModel[K comparable, V any](size int) fifo.Cache[K, V] {
+	return &model[K, V]{size: size, hand: -1}
+}
+
+func (m *model[K, V]) find(key K) int {
+	for i, e := range m.entries {
+		if e.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *model[K, V]) Set(key K, value V) {
+	if i := m.find(key); i >= 0 {
+		m.entries[i].value = value
+		m.entries[i].visited.Store(true)
+		return
+	}
+	if len(m.entries) >= m.size {
+		m.evict()
+	}
+	m.entries = append(m.entries, &entry[K, V]{key: key, value: value})
+}
+
+// evict sweeps the hand from the oldest entry to the newest, wrapping
+// around, clears the visited entries and evicts the first unvisited one.
+func (m *model[K, V]) evict() {
+	i := max(m.hand, 0)
+	for m.entries[i].visited.Load() {
+		m.entries[i].visited.Store(false)
+		i = (i + 1) % len(m.entries)
+	}
+	m.entries = append(m.entries[:i], m.entries[i+1:]...)
+	// the hand stays on the entry after the evicted one.
+	m.hand = i
+	if i == len(m.entries) {
+		m.hand = -1
+	}
+}
+
+func (m *model[K, V]) Get(key K) (value V, ok bool) {
+	i := m.find(key)
+	if i < 0 {
+		return value, false
+	}
+	m.entries[i].visited.Store(true)
+	return m.entries[i].value, true
+}
+
+func (m *model[K, V]) Contains(key K) bool {
+	return m.find(key) >= 0
+}
+
+func (m *model[K, V]) Peek(key K) (value V, ok bool) {
+	i := m.find(key)
+	if i < 0 {
+		return value, false
+	}
+	return m.entries[i].value, true
+}
+
+func (m *model[K, V]) Len() int {
+	return len(m.entries)
+}
+
+func (m *model[K, V]) Purge() {
+	m.entries = nil
+	m.hand = -1
+}
+
+func FuzzSieve(f *testing.F) {
+	fifotest.Fuzz(f, fifotest.Variant{
+		Name:     "sieve",
+		NewCache: New[int, int],
+		NewModel: newModel[int, int],
+	})
+}
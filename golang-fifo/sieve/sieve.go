@@ -2,16 +2,20 @@ package sieve
 
 import (
 	"container/list"
+	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/hey-kong/shift/golang-fifo"
+	"github.com/hey-kong/shift/golang-fifo/trace"
 )
 
 // entry holds the key and value of a cache entry.
+// visited is set atomically by concurrent Gets under the read lock.
 type entry[K comparable, V any] struct {
 	key     K
 	value   V
-	visited bool
+	visited atomic.Bool
 }
 
 type Sieve[K comparable, V any] struct {
@@ -20,6 +24,9 @@ type Sieve[K comparable, V any] struct {
 	items map[K]*list.Element
 	ll    *list.List
 	hand  *list.Element
+
+	tracer   trace.Sink[K]
+	traceSeq uint64
 }
 
 func New[K comparable, V any](size int) fifo.Cache[K, V] {
@@ -36,7 +43,7 @@ func (s *Sieve[K, V]) Set(key K, value V) {
 
 	if e, ok := s.items[key]; ok {
 		e.Value.(*entry[K, V]).value = value
-		e.Value.(*entry[K, V]).visited = true
+		e.Value.(*entry[K, V]).visited.Store(true)
 		return
 	}
 
@@ -45,13 +52,16 @@ func (s *Sieve[K, V]) Set(key K, value V) {
 	}
 	e := &entry[K, V]{key: key, value: value}
 	s.items[key] = s.ll.PushFront(e)
+	if s.tracer != nil {
+		s.record(trace.Insert, key, false)
+	}
 }
 
 func (s *Sieve[K, V]) Get(key K) (value V, ok bool) {
 	s.lock.RLock()
 	defer s.lock.RUnlock()
 	if e, ok := s.items[key]; ok {
-		e.Value.(*entry[K, V]).visited = true
+		e.Value.(*entry[K, V]).visited.Store(true)
 		return e.Value.(*entry[K, V]).value, true
 	}
 
@@ -89,6 +99,7 @@ func (s *Sieve[K, V]) Purge() {
 
 	s.items = make(map[K]*list.Element)
 	s.ll = list.New()
+	s.hand = nil
 }
 
 func (s *Sieve[K, V]) evict() {
@@ -98,8 +109,11 @@ func (s *Sieve[K, V]) evict() {
 		o = s.ll.Back()
 	}
 
-	for o.Value.(*entry[K, V]).visited {
-		o.Value.(*entry[K, V]).visited = false
+	for o.Value.(*entry[K, V]).visited.Load() {
+		o.Value.(*entry[K, V]).visited.Store(false)
+		if s.tracer != nil {
+			s.record(trace.ClearVisited, o.Value.(*entry[K, V]).key, false)
+		}
 		o = o.Prev()
 		if o == nil {
 			o = s.ll.Back()
@@ -109,4 +123,57 @@ func (s *Sieve[K, V]) evict() {
 	s.hand = o.Prev()
 	delete(s.items, o.Value.(*entry[K, V]).key)
 	s.ll.Remove(o)
+	if s.tracer != nil {
+		s.record(trace.Evict, o.Value.(*entry[K, V]).key, false)
+	}
+}
+
+// CheckInvariants returns an error if the internal state of the cache is
+// inconsistent. It walks every entry, it is meant for tests and debugging.
+func (s *Sieve[K, V]) CheckInvariants() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.ll.Len() > s.size {
+		return fmt.Errorf("sieve: %d entries, capacity %d", s.ll.Len(), s.size)
+	}
+	if len(s.items) != s.ll.Len() {
+		return fmt.Errorf("sieve: %d keys in the map, %d entries in the list", len(s.items), s.ll.Len())
+	}
+	hand := s.hand == nil
+	for e := s.ll.Front(); e != nil; e = e.Next() {
+		key := e.Value.(*entry[K, V]).key
+		if s.items[key] != e {
+			return fmt.Errorf("sieve: key %v maps to another element than its entry", key)
+		}
+		hand = hand || e == s.hand
+	}
+	if !hand {
+		return fmt.Errorf("sieve: the hand points at an element out of the list")
+	}
+	return nil
+}
+
+// SetTracer attaches a sink receiving the eviction-path decisions,
+// nil disables tracing.
+func (s *Sieve[K, V]) SetTracer(sink trace.Sink[K]) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tracer = sink
+}
+
+// record sends an event to the tracer, the frequency of SIEVE is its visited bit.
+func (s *Sieve[K, V]) record(kind trace.Kind, key K, visited bool) {
+	freq := 0
+	if visited {
+		freq = 1
+	}
+	s.traceSeq++
+	s.tracer.Record(trace.Event[K]{
+		Seq:  s.traceSeq,
+		Kind: kind,
+		Key:  key,
+		Freq: freq,
+	})
 }
@@ -3,6 +3,8 @@ package sieve
 import (
 	"testing"
 
+	"github.com/hey-kong/shift/golang-fifo/internal/fifotest"
+	"github.com/hey-kong/shift/golang-fifo/trace"
 	"github.com/stretchr/testify/require"
 )
 
@@ -43,3 +45,39 @@ func TestLenOnSieve(t *testing.T) {
 	cache.Set(2, 2)
 	require.Equal(t, 2, cache.Len())
 }
+
+func TestTraceOnSieve(t *testing.T) {
+	cache := New[int, int](3)
+	r := trace.NewRecorder[int]()
+	require.True(t, trace.Attach(cache, trace.Sink[int](r)))
+
+	cache.Set(1, 1)
+	cache.Set(2, 2)
+	cache.Set(3, 3)
+	cache.Get(1)
+	cache.Set(4, 4)
+
+	timeline := trace.Timeline(r.Events(), 1)
+	require.Len(t, timeline, 2)
+	require.Equal(t, trace.ClearVisited, timeline[1].Kind)
+
+	timeline = trace.Timeline(r.Events(), 2)
+	require.Len(t, timeline, 2)
+	require.Equal(t, trace.Evict, timeline[1].Kind)
+}
+
+func TestConformanceOnSieve(t *testing.T) {
+	fifotest.Run(t, New[int, int])
+}
+
+func BenchmarkHitOnSieve(b *testing.B) {
+	fifotest.BenchmarkHit(b, New[int, int])
+}
+
+func BenchmarkMissOnSieve(b *testing.B) {
+	fifotest.BenchmarkMiss(b, New[int, int])
+}
+
+func BenchmarkMixedOnSieve(b *testing.B) {
+	fifotest.BenchmarkMixed(b, New[int, int])
+}
@@ -0,0 +1,99 @@
+package slru
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/hey-kong/shift/golang-fifo"
+	"github.com/hey-kong/shift/golang-fifo/internal/fifotest"
+)
+
+// model is a slow SLRU written with slices, least recently used entry
+// first, as the reference of SLRU.
+type model[K comparable, V any] struct {
+	probation     []*entry[K, V]
+	protected     []*entry[K, V]
+	probationSize int
+	protectedSize int
+}
+
+func newModel[K comparable, V any](size int) fifo.Cache[K, V] {
+	probationSize := max(int(DefaultProbationRatio*float64(size)), min(size, 1))
+	return &model[K, V]{
+		probationSize: probationSize,
+		protectedSize: size - probationSize,
+	}
+}
+
+func index[K comparable, V any](segment []*entry[K, V], key K) int {
+	return slices.IndexFunc(segment, func(e *entry[K, V]) bool { return e.key == key })
+}
+
+// touch moves the entry of key to the most recent end of protected, from
+// either segment, and returns it. The least recent protected entry is
+// evicted when protected overflows.
+func (m *model[K, V]) touch(key K) *entry[K, V] {
+	var e *entry[K, V]
+	if i := index(m.protected, key); i >= 0 {
+		e = m.protected[i]
+		m.protected = slices.Delete(m.protected, i, i+1)
+		m.protected = append(m.protected, e)
+		return e
+	}
+	if i := index(m.probation, key); i >= 0 {
+		e = m.probation[i]
+		m.probation = slices.Delete(m.probation, i, i+1)
+		m.protected = append(m.protected, e)
+		if len(m.protected) > m.protectedSize {
+			m.protected = m.protected[1:]
+		}
+	}
+	return e
+}
+
+func (m *model[K, V]) Set(key K, value V) {
+	if e := m.touch(key); e != nil {
+		e.value = value
+		return
+	}
+	if len(m.probation) >= m.probationSize {
+		m.probation = m.probation[1:]
+	}
+	m.probation = append(m.probation, &entry[K, V]{key: key, value: value})
+}
+
+func (m *model[K, V]) Get(key K) (value V, ok bool) {
+	if e := m.touch(key); e != nil {
+		return e.value, true
+	}
+	return value, false
+}
+
+func (m *model[K, V]) Contains(key K) bool {
+	return index(m.probation, key) >= 0 || index(m.protected, key) >= 0
+}
+
+func (m *model[K, V]) Peek(key K) (value V, ok bool) {
+	for _, segment := range [][]*entry[K, V]{m.probation, m.protected} {
+		if i := index(segment, key); i >= 0 {
+			return segment[i].value, true
+		}
+	}
+	return value, false
+}
+
+func (m *model[K, V]) Len() int {
+	return len(m.probation) + len(m.protected)
+}
+
+func (m *model[K, V]) Purge() {
+	m.probation, m.protected = nil, nil
+}
+
+func FuzzSLRU(f *testing.F) {
+	fifotest.Fuzz(f, fifotest.Variant{
+		Name:     "slru",
+		NewCache: New[int, int],
+		NewModel: newModel[int, int],
+	})
+}
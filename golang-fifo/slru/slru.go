@@ -4,10 +4,12 @@ package slru
 // SLRU follows the LRU principle instead of FIFO.
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/hey-kong/shift/golang-fifo"
 	"github.com/hey-kong/shift/golang-fifo/slru/list"
+	"github.com/hey-kong/shift/golang-fifo/trace"
 )
 
 const (
@@ -28,16 +30,21 @@ type SLRU[K comparable, V any] struct {
 	protected     *list.List
 	probationSize int
 	protectedSize int
+
+	tracer   trace.Sink[K]
+	traceSeq uint64
 }
 
 func New[K comparable, V any](size int) fifo.Cache[K, V] {
+	// a small cache still needs room for new entries on probation.
+	probationSize := max(int(DefaultProbationRatio*float64(size)), min(size, 1))
 	return &SLRU[K, V]{
 		size:          size,
 		items:         make(map[K]*list.Element),
 		probation:     list.New(),
 		protected:     list.New(),
-		probationSize: int(DefaultProbationRatio * float64(size)),
-		protectedSize: size - int(DefaultProbationRatio*float64(size)),
+		probationSize: probationSize,
+		protectedSize: size - probationSize,
 	}
 }
 
@@ -52,6 +59,9 @@ func (s *SLRU[K, V]) Set(key K, value V) {
 		if e.List() == s.probation {
 			s.items[e.Value.(*entry[K, V]).key] = s.protected.PushFront(e.Value)
 			s.probation.Remove(e)
+			if s.tracer != nil {
+				s.record(trace.Promote, e.Value.(*entry[K, V]).key, "probation", "protected")
+			}
 			if s.protected.Len() > s.protectedSize {
 				s.evict(s.protected)
 			}
@@ -65,6 +75,9 @@ func (s *SLRU[K, V]) Set(key K, value V) {
 	}
 	e := &entry[K, V]{key: key, value: value}
 	s.items[key] = s.probation.PushFront(e)
+	if s.tracer != nil {
+		s.record(trace.Insert, key, "", "probation")
+	}
 }
 
 func (s *SLRU[K, V]) Get(key K) (value V, ok bool) {
@@ -77,6 +90,9 @@ func (s *SLRU[K, V]) Get(key K) (value V, ok bool) {
 		if e.List() == s.probation {
 			s.items[e.Value.(*entry[K, V]).key] = s.protected.PushFront(e.Value)
 			s.probation.Remove(e)
+			if s.tracer != nil {
+				s.record(trace.Promote, e.Value.(*entry[K, V]).key, "probation", "protected")
+			}
 			if s.protected.Len() > s.protectedSize {
 				s.evict(s.protected)
 			}
@@ -126,4 +142,60 @@ func (s *SLRU[K, V]) evict(l *list.List) {
 	key := o.Value.(*entry[K, V]).key
 	delete(s.items, key)
 	l.Remove(o)
+	if s.tracer != nil {
+		from := "probation"
+		if l == s.protected {
+			from = "protected"
+		}
+		s.record(trace.Evict, key, from, "")
+	}
+}
+
+// CheckInvariants returns an error if the internal state of the cache is
+// inconsistent. It walks every entry, it is meant for tests and debugging.
+func (s *SLRU[K, V]) CheckInvariants() error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	if s.probation.Len() > s.probationSize {
+		return fmt.Errorf("slru: %d entries on probation, segment size %d", s.probation.Len(), s.probationSize)
+	}
+	if s.protected.Len() > s.protectedSize {
+		return fmt.Errorf("slru: %d protected entries, segment size %d", s.protected.Len(), s.protectedSize)
+	}
+	if n := s.probation.Len() + s.protected.Len(); n > s.size {
+		return fmt.Errorf("slru: %d entries, capacity %d", n, s.size)
+	}
+	if n := s.probation.Len() + s.protected.Len(); len(s.items) != n {
+		return fmt.Errorf("slru: %d keys in the map, %d entries in the segments", len(s.items), n)
+	}
+	for _, segment := range []*list.List{s.probation, s.protected} {
+		for e := segment.Front(); e != nil; e = e.Next() {
+			key := e.Value.(*entry[K, V]).key
+			if el := s.items[key]; el != e || el.List() != segment {
+				return fmt.Errorf("slru: key %v maps to another element than its entry", key)
+			}
+		}
+	}
+	return nil
+}
+
+// SetTracer attaches a sink receiving the eviction-path decisions,
+// nil disables tracing.
+func (s *SLRU[K, V]) SetTracer(sink trace.Sink[K]) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.tracer = sink
+}
+
+func (s *SLRU[K, V]) record(kind trace.Kind, key K, from, to string) {
+	s.traceSeq++
+	s.tracer.Record(trace.Event[K]{
+		Seq:  s.traceSeq,
+		Kind: kind,
+		Key:  key,
+		From: from,
+		To:   to,
+	})
 }
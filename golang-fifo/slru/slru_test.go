@@ -3,6 +3,8 @@ package slru
 import (
 	"testing"
 
+	"github.com/hey-kong/shift/golang-fifo/internal/fifotest"
+	"github.com/hey-kong/shift/golang-fifo/trace"
 	"github.com/stretchr/testify/require"
 )
 
@@ -43,3 +45,41 @@ func TestLenOnSLRU(t *testing.T) {
 	cache.Set(2, 2)
 	require.Equal(t, 2, cache.Len())
 }
+
+func TestTraceOnSLRU(t *testing.T) {
+	cache := New[int, int](10)
+	r := trace.NewRecorder[int]()
+	require.True(t, trace.Attach(cache, trace.Sink[int](r)))
+
+	cache.Set(1, 1)
+	cache.Get(1)
+	cache.Set(2, 2)
+	cache.Set(3, 3)
+	cache.Set(4, 4)
+
+	timeline := trace.Timeline(r.Events(), 1)
+	require.Len(t, timeline, 2)
+	require.Equal(t, trace.Promote, timeline[1].Kind)
+	require.Equal(t, "protected", timeline[1].To)
+
+	timeline = trace.Timeline(r.Events(), 2)
+	require.Len(t, timeline, 2)
+	require.Equal(t, trace.Evict, timeline[1].Kind)
+	require.Equal(t, "probation", timeline[1].From)
+}
+
+func TestConformanceOnSLRU(t *testing.T) {
+	fifotest.Run(t, New[int, int])
+}
+
+func BenchmarkHitOnSLRU(b *testing.B) {
+	fifotest.BenchmarkHit(b, New[int, int])
+}
+
+func BenchmarkMissOnSLRU(b *testing.B) {
+	fifotest.BenchmarkMiss(b, New[int, int])
+}
+
+func BenchmarkMixedOnSLRU(b *testing.B) {
+	fifotest.BenchmarkMixed(b, New[int, int])
+}
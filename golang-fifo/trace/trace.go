@@ -0,0 +1,171 @@
+// Package trace records the decisions taken on the eviction path of the
+// golang-fifo policies, to explain why a key was evicted.
+//
+// Tracing is opt-in: a cache only builds events once a Sink is attached
+// with Attach, otherwise it costs a nil check.
+package trace
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/hey-kong/shift/golang-fifo"
+)
+
+// Kind is the kind of decision an Event records.
+type Kind uint8
+
+const (
+	// Insert is a new key added to a queue.
+	Insert Kind = iota
+	// Evict is a key removed from the cache.
+	Evict
+	// Requeue is a key moved from the tail of a queue to the head of another
+	// (or the same) queue, with its aged frequency.
+	Requeue
+	// Promote is a key moved to a queue of more valuable entries,
+	// e.g. from the small to the main queue of S3FIFO.
+	Promote
+	// ClearVisited is a SIEVE hand passing over a visited key.
+	ClearVisited
+	// Rotate is Shift's drained eviction queue moved behind the retention queues.
+	Rotate
+	// ShiftOn is Shift starting to insert new keys into the retention queue.
+	ShiftOn
+	// ShiftOff is Shift inserting new keys into the eviction queue again.
+	ShiftOff
+	// Decay is the frequency of every key being aged at once.
+	Decay
+)
+
+var kindNames = [...]string{
+	Insert:       "insert",
+	Evict:        "evict",
+	Requeue:      "requeue",
+	Promote:      "promote",
+	ClearVisited: "clear-visited",
+	Rotate:       "rotate",
+	ShiftOn:      "shift-on",
+	ShiftOff:     "shift-off",
+	Decay:        "decay",
+}
+
+func (k Kind) String() string {
+	if int(k) < len(kindNames) {
+		return kindNames[k]
+	}
+	return fmt.Sprintf("kind(%d)", k)
+}
+
+// Keyed reports whether events of this kind are about a single key.
+func (k Kind) Keyed() bool {
+	return k <= ClearVisited
+}
+
+// Event is a single decision on the eviction path.
+type Event[K comparable] struct {
+	// Seq orders the events of one cache.
+	Seq  uint64
+	Kind Kind
+	// Key is the zero value for events that are not Keyed.
+	Key K
+	// Freq is the frequency of the key after the decision.
+	Freq int
+	// From and To name the queues involved, if any.
+	From string
+	To   string
+}
+
+func (e Event[K]) String() string {
+	s := fmt.Sprintf("#%d %s", e.Seq, e.Kind)
+	if e.Kind.Keyed() {
+		s += fmt.Sprintf(" key=%v freq=%d", e.Key, e.Freq)
+	}
+	if e.From != "" || e.To != "" {
+		s += fmt.Sprintf(" %s->%s", e.From, e.To)
+	}
+	return s
+}
+
+// Sink receives the events of a cache.
+// Record is called with the lock of the cache held, it must not call back
+// into the cache.
+type Sink[K comparable] interface {
+	Record(e Event[K])
+}
+
+// SinkFunc adapts a function to a Sink.
+type SinkFunc[K comparable] func(e Event[K])
+
+func (f SinkFunc[K]) Record(e Event[K]) {
+	f(e)
+}
+
+// Traceable is implemented by the caches that support tracing.
+type Traceable[K comparable] interface {
+	// SetTracer attaches a sink to the cache, nil disables tracing.
+	SetTracer(sink Sink[K])
+}
+
+// Attach attaches sink to cache, it reports whether cache supports tracing.
+func Attach[K comparable, V any](cache fifo.Cache[K, V], sink Sink[K]) bool {
+	t, ok := cache.(Traceable[K])
+	if ok {
+		t.SetTracer(sink)
+	}
+	return ok
+}
+
+// Recorder is a Sink keeping every event in memory.
+// It is safe to share between caches.
+type Recorder[K comparable] struct {
+	mu     sync.Mutex
+	events []Event[K]
+}
+
+func NewRecorder[K comparable]() *Recorder[K] {
+	return &Recorder[K]{}
+}
+
+func (r *Recorder[K]) Record(e Event[K]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// Events returns a copy of the recorded events.
+func (r *Recorder[K]) Events() []Event[K] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Event[K](nil), r.events...)
+}
+
+// Reset drops the recorded events.
+func (r *Recorder[K]) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+// Timeline returns the lifecycle of key: the events about it in order.
+func Timeline[K comparable](events []Event[K], key K) []Event[K] {
+	var timeline []Event[K]
+	for _, e := range events {
+		if e.Kind.Keyed() && e.Key == key {
+			timeline = append(timeline, e)
+		}
+	}
+	return timeline
+}
+
+// Timelines groups the events by key, keeping them in order.
+// Events that are not Keyed are left out.
+func Timelines[K comparable](events []Event[K]) map[K][]Event[K] {
+	timelines := make(map[K][]Event[K])
+	for _, e := range events {
+		if e.Kind.Keyed() {
+			timelines[e.Key] = append(timelines[e.Key], e)
+		}
+	}
+	return timelines
+}
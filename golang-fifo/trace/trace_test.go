@@ -0,0 +1,51 @@
+package trace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderAndTimeline(t *testing.T) {
+	r := NewRecorder[int]()
+	r.Record(Event[int]{Seq: 1, Kind: Insert, Key: 1, To: "small"})
+	r.Record(Event[int]{Seq: 2, Kind: Insert, Key: 2, To: "small"})
+	r.Record(Event[int]{Seq: 3, Kind: Rotate, From: "eviction", To: "retention"})
+	r.Record(Event[int]{Seq: 4, Kind: Evict, Key: 1, From: "small", To: "ghost"})
+
+	events := r.Events()
+	require.Len(t, events, 4)
+
+	timeline := Timeline(events, 1)
+	require.Len(t, timeline, 2)
+	require.Equal(t, Insert, timeline[0].Kind)
+	require.Equal(t, Evict, timeline[1].Kind)
+
+	// the zero key must not collect the events that are not about a key.
+	require.Empty(t, Timeline(events, 0))
+
+	timelines := Timelines(events)
+	require.Len(t, timelines, 2)
+	require.Len(t, timelines[2], 1)
+
+	r.Reset()
+	require.Empty(t, r.Events())
+}
+
+func TestEventString(t *testing.T) {
+	e := Event[string]{Seq: 7, Kind: Requeue, Key: "a", Freq: 1, From: "eviction", To: "retention"}
+	require.Equal(t, "#7 requeue key=a freq=1 eviction->retention", e.String())
+
+	e = Event[string]{Seq: 8, Kind: ShiftOn}
+	require.Equal(t, "#8 shift-on", e.String())
+	require.Equal(t, "kind(200)", Kind(200).String())
+}
+
+func TestSinkFunc(t *testing.T) {
+	var got []Event[int]
+	var sink Sink[int] = SinkFunc[int](func(e Event[int]) {
+		got = append(got, e)
+	})
+	sink.Record(Event[int]{Kind: Evict, Key: 3})
+	require.Equal(t, []Event[int]{{Kind: Evict, Key: 3}}, got)
+}